@@ -17,16 +17,56 @@ const (
 
 // Config is the object that holds all the configuration parameters of the server, and holds all the information necessary to create command-line flags for them
 type Config struct {
-	LogLevel             string   `mapstructure:"LOG_LEVEL" name:"log-level" long:"log-level" defaultValue:"info" help:"Logging level, can only be one of 'panic', 'fatal', 'error', 'warn', 'info', 'debug', 'trace'."`
-	ServerAddress        string   `mapstructure:"SERVER_ADDRESS" name:"server-address" long:"server-address" defaultValue:"127.0.0.1" help:"The address that the web server will be listening to"`
-	ServerPort           string   `mapstructure:"SERVER_PORT" name:"server-port" long:"server-port" defaultValue:"8080" help:"The port that the web server will be listening to"`
-	ShutDownTimeout      int      `mapstructure:"SHUTDOWN_TIMEOUT" name:"shutdown-timeout" long:"shutdown-timeout" defaultValue:"10" help:"The timeout (in seconds) for the server to shut down"`
-	CORSAllowOrigins     []string `mapstructure:"CORS_ALLOW_ORIGINS" name:"cors-allow-origins" long:"cors-allow-origins" defaultValue:"*" help:"Allow origins for CORS configuration"`
-	CORSAllowMethods     []string `mapstructure:"CORS_ALLOW_METHODS" name:"cors-allow-methods" long:"cors-allow-methods" defaultValue:"GET POST PUT DELETE" help:"List of CORS methods that are allowed"`
-	CORSAllowHeaders     []string `mapstructure:"CORS_ALLOW_HEADERS" name:"cors-allow-headers" long:"cors-allow-headers" defaultValue:"Origin content-type" help:"List of CORS headers that are allowed"`
-	CORSExposeHeaders    []string `mapstructure:"CORS_EXPOSE_HEADERS" name:"cors-expose-headers" long:"cors-expose-headers" defaultValue:"Content-Length" help:"List of CORS headers that are exposed"`
-	CORSAllowCredentials bool     `mapstructure:"CORS_ALLOW_CREDENTIALS" name:"cors-allow-credentials" long:"cors-allow-credentials" defaultValue:"false" help:"Whether to allow credentials to CORS"`
-	CORSMaxAge           int      `mapstructure:"CORS_MAX_AGE" name:"cors-max-age" long:"cors-max-age" defaultValue:"1" help:"Maximum age (in hours) pertaining to CORS configuration"`
+	LogLevel               string   `mapstructure:"LOG_LEVEL" name:"log-level" long:"log-level" defaultValue:"info" help:"Logging level, can only be one of 'panic', 'fatal', 'error', 'warn', 'info', 'debug', 'trace'."`
+	ServerAddress          string   `mapstructure:"SERVER_ADDRESS" name:"server-address" long:"server-address" defaultValue:"127.0.0.1" help:"The address that the web server will be listening to"`
+	ServerPort             string   `mapstructure:"SERVER_PORT" name:"server-port" long:"server-port" defaultValue:"8080" help:"The port that the web server will be listening to"`
+	ShutDownTimeout        int      `mapstructure:"SHUTDOWN_TIMEOUT" name:"shutdown-timeout" long:"shutdown-timeout" defaultValue:"10" help:"The timeout (in seconds) for the server to shut down"`
+	CORSAllowOrigins       []string `mapstructure:"CORS_ALLOW_ORIGINS" name:"cors-allow-origins" long:"cors-allow-origins" defaultValue:"*" help:"Allow origins for CORS configuration"`
+	CORSAllowMethods       []string `mapstructure:"CORS_ALLOW_METHODS" name:"cors-allow-methods" long:"cors-allow-methods" defaultValue:"GET POST PUT DELETE" help:"List of CORS methods that are allowed"`
+	CORSAllowHeaders       []string `mapstructure:"CORS_ALLOW_HEADERS" name:"cors-allow-headers" long:"cors-allow-headers" defaultValue:"Origin content-type" help:"List of CORS headers that are allowed"`
+	CORSExposeHeaders      []string `mapstructure:"CORS_EXPOSE_HEADERS" name:"cors-expose-headers" long:"cors-expose-headers" defaultValue:"Content-Length Link RateLimit-Limit RateLimit-Remaining RateLimit-Reset Retry-After" help:"List of CORS headers that are exposed"`
+	CORSAllowCredentials   bool     `mapstructure:"CORS_ALLOW_CREDENTIALS" name:"cors-allow-credentials" long:"cors-allow-credentials" defaultValue:"false" help:"Whether to allow credentials to CORS"`
+	CORSMaxAge             int      `mapstructure:"CORS_MAX_AGE" name:"cors-max-age" long:"cors-max-age" defaultValue:"1" help:"Maximum age (in hours) pertaining to CORS configuration"`
+	FetchWorkers           int      `mapstructure:"FETCH_WORKERS" name:"fetch-workers" long:"fetch-workers" defaultValue:"8" help:"Maximum number of comics that are fetched concurrently for a single request"`
+	UpstreamURL            string   `mapstructure:"UPSTREAM_URL" name:"upstream-url" long:"upstream-url" defaultValue:"https://xkcd.com" help:"Base URL of the xkcd compatible server that the comics are retrieved from"`
+	UpstreamTimeout        int      `mapstructure:"UPSTREAM_TIMEOUT" name:"upstream-timeout" long:"upstream-timeout" defaultValue:"10" help:"The timeout (in seconds) of a single attempt of a request made to the upstream server"`
+	UpstreamUserAgent      string   `mapstructure:"UPSTREAM_USER_AGENT" name:"upstream-user-agent" long:"upstream-user-agent" defaultValue:"go-api-k8s" help:"The User-Agent header sent along with the requests made to the upstream server"`
+	UpstreamRetryAttempts  int      `mapstructure:"UPSTREAM_RETRY_ATTEMPTS" name:"upstream-retry-attempts" long:"upstream-retry-attempts" defaultValue:"3" help:"Maximum number of attempts of a request made to the upstream server"`
+	UpstreamRetryBaseDelay int      `mapstructure:"UPSTREAM_RETRY_BASE_DELAY" name:"upstream-retry-base-delay" long:"upstream-retry-base-delay" defaultValue:"200" help:"The delay (in milliseconds) before the first retry of a request made to the upstream server, doubled on every retry"`
+	UpstreamRetryMaxDelay  int      `mapstructure:"UPSTREAM_RETRY_MAX_DELAY" name:"upstream-retry-max-delay" long:"upstream-retry-max-delay" defaultValue:"5000" help:"Maximum delay (in milliseconds) in between two attempts of a request made to the upstream server"`
+	UpstreamRetryJitter    bool     `mapstructure:"UPSTREAM_RETRY_JITTER" name:"upstream-retry-jitter" long:"upstream-retry-jitter" defaultValue:"true" help:"Whether to randomize the delay in between two attempts of a request made to the upstream server"`
+	UpstreamRetryStatuses  []string `mapstructure:"UPSTREAM_RETRY_STATUSES" name:"upstream-retry-statuses" long:"upstream-retry-statuses" defaultValue:"429 502 503 504" help:"List of upstream response status codes that are retried"`
+	BreakerFailureRatio    int      `mapstructure:"BREAKER_FAILURE_RATIO" name:"breaker-failure-ratio" long:"breaker-failure-ratio" defaultValue:"50" help:"Percentage of failed upstream requests within a window that opens the circuit breaker, 0 disables the breaker"`
+	BreakerMinRequests     int      `mapstructure:"BREAKER_MIN_REQUESTS" name:"breaker-min-requests" long:"breaker-min-requests" defaultValue:"10" help:"Minimum number of upstream requests within a window before the circuit breaker may open"`
+	BreakerWindow          int      `mapstructure:"BREAKER_WINDOW" name:"breaker-window" long:"breaker-window" defaultValue:"60" help:"The window (in seconds) over which the failed upstream requests are counted"`
+	BreakerCooldown        int      `mapstructure:"BREAKER_COOLDOWN" name:"breaker-cooldown" long:"breaker-cooldown" defaultValue:"30" help:"The time (in seconds) that the circuit breaker stays open before letting a trial request through"`
+	UpstreamDir            string   `mapstructure:"UPSTREAM_DIR" name:"upstream-dir" long:"upstream-dir" defaultValue:"" help:"Directory of 'N/info.0.json' files to read the comics from instead of the upstream server"`
+	CacheSize              int      `mapstructure:"CACHE_SIZE" name:"cache-size" long:"cache-size" defaultValue:"5000" help:"Maximum number of comics kept in the in-memory cache, 0 disables the cache"`
+	CacheTTL               int      `mapstructure:"CACHE_TTL" name:"cache-ttl" long:"cache-ttl" defaultValue:"86400" help:"The time (in seconds) that a comic is kept in the in-memory cache, 0 keeps it until it is evicted"`
+	DataDir                string   `mapstructure:"DATA_DIR" name:"data-dir" long:"data-dir" defaultValue:"" help:"Directory of the persistent comic store, the store and its background sync are disabled when empty"`
+	SyncInterval           int      `mapstructure:"SYNC_INTERVAL" name:"sync-interval" long:"sync-interval" defaultValue:"3600" help:"The interval (in seconds) at which the persistent comic store is synchronized with upstream, 0 disables the synchronization"`
+	MaxPageSize            int      `mapstructure:"MAX_PAGE_SIZE" name:"max-page-size" long:"max-page-size" defaultValue:"100" help:"The maximum number of comics returned in a single page of the requests using limit, offset or cursor, pages are unbounded when set to 0"`
+	MaxRangeSize           int      `mapstructure:"MAX_RANGE_SIZE" name:"max-range-size" long:"max-range-size" defaultValue:"5000" help:"The maximum number of comics in the range of a single request, ranges are unbounded when set to 0"`
+	StatsCacheTTL          int      `mapstructure:"STATS_CACHE_TTL" name:"stats-cache-ttl" long:"stats-cache-ttl" defaultValue:"300" help:"The time (in seconds) that the statistics of a query are cached, 0 disables the cache"`
+	ImageUpstreamURL       string   `mapstructure:"IMAGE_UPSTREAM_URL" name:"image-upstream-url" long:"image-upstream-url" defaultValue:"" help:"Base URL of the server that the comic images are retrieved from instead of the host of their URL, e.g. a mirror of imgs.xkcd.com"`
+	ImageCacheDir          string   `mapstructure:"IMAGE_CACHE_DIR" name:"image-cache-dir" long:"image-cache-dir" defaultValue:"" help:"Directory of the on-disk cache of the comic images and thumbnails, the images are not cached when empty"`
+	ImageCacheSize         int      `mapstructure:"IMAGE_CACHE_SIZE" name:"image-cache-size" long:"image-cache-size" defaultValue:"256" help:"Maximum size (in megabytes) of the on-disk cache of the comic images, the least recently used ones being evicted first"`
+	ImageMaxAge            int      `mapstructure:"IMAGE_MAX_AGE" name:"image-max-age" long:"image-max-age" defaultValue:"604800" help:"The time (in seconds) that clients may cache the comic images for"`
+	RewriteImageURLs       bool     `mapstructure:"REWRITE_IMAGE_URLS" name:"rewrite-image-urls" long:"rewrite-image-urls" defaultValue:"false" help:"Whether to rewrite the image URLs of the comics so that they point to the image proxy of the API"`
+	PublicBaseURL          string   `mapstructure:"PUBLIC_BASE_URL" name:"public-base-url" long:"public-base-url" defaultValue:"" help:"Base URL that the clients reach the API at, e.g. https://api.example.com, which the absolute URLs of the feeds and of the rewritten images are built from instead of the Host header of the requests"`
+	HTTPCacheMaxAge        int      `mapstructure:"HTTP_CACHE_MAX_AGE" name:"http-cache-max-age" long:"http-cache-max-age" defaultValue:"300" help:"The time (in seconds) that clients may cache the responses for, unless overridden for their route, 0 makes them revalidate every time"`
+	HTTPCacheRouteMaxAges  []string `mapstructure:"HTTP_CACHE_ROUTE_MAX_AGES" name:"http-cache-route-max-ages" long:"http-cache-route-max-ages" defaultValue:"/comics/latest=60 /comics/on-this-day=3600 /comics/:num=86400 /comics/:num/transcript=86400 /ping=0" help:"List of ROUTE=SECONDS overrides of the time that clients may cache the responses of a route for"`
+	CompressionLevel       int      `mapstructure:"COMPRESSION_LEVEL" name:"compression-level" long:"compression-level" defaultValue:"5" help:"The level in between 1 (fastest) and 9 (smallest) that the responses are compressed at, 0 disables the compression"`
+	CompressionMinSize     int      `mapstructure:"COMPRESSION_MIN_SIZE" name:"compression-min-size" long:"compression-min-size" defaultValue:"1024" help:"Minimum size (in bytes) of the responses that are compressed"`
+	CompressionExclude     []string `mapstructure:"COMPRESSION_EXCLUDE" name:"compression-exclude" long:"compression-exclude" defaultValue:"image/ audio/ video/ application/gzip application/zip" help:"List of the prefixes of the media types of the responses that are never compressed"`
+	RateLimitPerMinute     int      `mapstructure:"RATE_LIMIT_PER_MINUTE" name:"rate-limit-per-minute" long:"rate-limit-per-minute" defaultValue:"600" help:"Number of requests per minute that a client may make, 0 disables the rate limiting"`
+	RateLimitBurst         int      `mapstructure:"RATE_LIMIT_BURST" name:"rate-limit-burst" long:"rate-limit-burst" defaultValue:"60" help:"Maximum number of requests that a client may make at once"`
+	RateLimitKey           string   `mapstructure:"RATE_LIMIT_KEY" name:"rate-limit-key" long:"rate-limit-key" defaultValue:"ip" help:"How the clients are identified, one of 'ip', 'api-key' for the X-API-Key header or 'header:NAME', the clients lacking the header or sending an unknown key being identified by IP"`
+	RateLimitKeys          []string `mapstructure:"RATE_LIMIT_KEYS" name:"rate-limit-keys" long:"rate-limit-keys" defaultValue:"" sensitive:"true" help:"List of the keys that identify the clients when they are not identified by IP, any other key being ignored since the clients could otherwise pick a new one to get a new bucket"`
+	RateLimitRoutes        []string `mapstructure:"RATE_LIMIT_ROUTES" name:"rate-limit-routes" long:"rate-limit-routes" defaultValue:"/ping=0 /search=120/20 /stats=60/10" help:"List of ROUTE=RATE[/BURST] overrides of the rate limit of a route, whose requests are counted apart, 0 disabling the limit"`
+	RateLimitMaxClients    int      `mapstructure:"RATE_LIMIT_MAX_CLIENTS" name:"rate-limit-max-clients" long:"rate-limit-max-clients" defaultValue:"10000" help:"Maximum number of clients whose requests are tracked, the least recently seen ones being forgotten first"`
+	TrustedProxies         []string `mapstructure:"TRUSTED_PROXIES" name:"trusted-proxies" long:"trusted-proxies" defaultValue:"" help:"List of the IP addresses or CIDR ranges of the proxies whose X-Forwarded-For and X-Real-IP headers are trusted to tell the IP address of the clients, the remote address being used otherwise"`
+	AdminToken             string   `mapstructure:"ADMIN_TOKEN" name:"admin-token" long:"admin-token" defaultValue:"" sensitive:"true" help:"The bearer token required by the administration endpoints, which are unreachable when empty"`
 }
 
 // NewConfig returns an instance of the Config
@@ -25,7 +25,7 @@ func (us *ConfigUnitSuite) TestPrintConfig() {
 			config: Config{
 				LogLevel: "info",
 			},
-			expectedString: "LogLevel: info\nServerAddress: \nServerPort: \nShutDownTimeout: 0\nCORSAllowOrigins: []\nCORSAllowMethods: []\nCORSAllowHeaders: []\nCORSExposeHeaders: []\nCORSAllowCredentials: false\nCORSMaxAge: 0\n",
+			expectedString: "LogLevel: info\nServerAddress: \nServerPort: \nShutDownTimeout: 0\nCORSAllowOrigins: []\nCORSAllowMethods: []\nCORSAllowHeaders: []\nCORSExposeHeaders: []\nCORSAllowCredentials: false\nCORSMaxAge: 0\nFetchWorkers: 0\nUpstreamURL: \nUpstreamTimeout: 0\nUpstreamUserAgent: \nUpstreamRetryAttempts: 0\nUpstreamRetryBaseDelay: 0\nUpstreamRetryMaxDelay: 0\nUpstreamRetryJitter: false\nUpstreamRetryStatuses: []\nBreakerFailureRatio: 0\nBreakerMinRequests: 0\nBreakerWindow: 0\nBreakerCooldown: 0\nUpstreamDir: \nCacheSize: 0\nCacheTTL: 0\nDataDir: \nSyncInterval: 0\nMaxPageSize: 0\nMaxRangeSize: 0\nStatsCacheTTL: 0\nImageUpstreamURL: \nImageCacheDir: \nImageCacheSize: 0\nImageMaxAge: 0\nRewriteImageURLs: false\nPublicBaseURL: \nHTTPCacheMaxAge: 0\nHTTPCacheRouteMaxAges: []\nCompressionLevel: 0\nCompressionMinSize: 0\nCompressionExclude: []\nRateLimitPerMinute: 0\nRateLimitBurst: 0\nRateLimitKey: \nRateLimitRoutes: []\nRateLimitMaxClients: 0\nTrustedProxies: []\n",
 		},
 		{
 			name: "Config with multiple fields passed",
@@ -35,7 +35,7 @@ func (us *ConfigUnitSuite) TestPrintConfig() {
 				CORSAllowMethods: []string{"PUT"},
 				CORSAllowHeaders: []string{"Origin content-type"},
 			},
-			expectedString: "LogLevel: info\nServerAddress: \nServerPort: \nShutDownTimeout: 0\nCORSAllowOrigins: [http://127.0.0.1]\nCORSAllowMethods: [PUT]\nCORSAllowHeaders: [Origin content-type]\nCORSExposeHeaders: []\nCORSAllowCredentials: false\nCORSMaxAge: 0\n",
+			expectedString: "LogLevel: info\nServerAddress: \nServerPort: \nShutDownTimeout: 0\nCORSAllowOrigins: [http://127.0.0.1]\nCORSAllowMethods: [PUT]\nCORSAllowHeaders: [Origin content-type]\nCORSExposeHeaders: []\nCORSAllowCredentials: false\nCORSMaxAge: 0\nFetchWorkers: 0\nUpstreamURL: \nUpstreamTimeout: 0\nUpstreamUserAgent: \nUpstreamRetryAttempts: 0\nUpstreamRetryBaseDelay: 0\nUpstreamRetryMaxDelay: 0\nUpstreamRetryJitter: false\nUpstreamRetryStatuses: []\nBreakerFailureRatio: 0\nBreakerMinRequests: 0\nBreakerWindow: 0\nBreakerCooldown: 0\nUpstreamDir: \nCacheSize: 0\nCacheTTL: 0\nDataDir: \nSyncInterval: 0\nMaxPageSize: 0\nMaxRangeSize: 0\nStatsCacheTTL: 0\nImageUpstreamURL: \nImageCacheDir: \nImageCacheSize: 0\nImageMaxAge: 0\nRewriteImageURLs: false\nPublicBaseURL: \nHTTPCacheMaxAge: 0\nHTTPCacheRouteMaxAges: []\nCompressionLevel: 0\nCompressionMinSize: 0\nCompressionExclude: []\nRateLimitPerMinute: 0\nRateLimitBurst: 0\nRateLimitKey: \nRateLimitRoutes: []\nRateLimitMaxClients: 0\nTrustedProxies: []\n",
 		},
 	}
 
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// stopWords are the words too common to be worth indexing
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "if": true, "in": true, "into": true, "is": true, "it": true, "no": true,
+	"not": true, "of": true, "on": true, "or": true, "such": true, "that": true, "the": true,
+	"their": true, "then": true, "there": true, "these": true, "they": true, "this": true, "to": true,
+	"was": true, "will": true, "with": true,
+}
+
+// token is a word of a text, along with its position in the text
+type token struct {
+	// term is the normalized and stemmed form of the word, under which it is indexed
+	term string
+	// start and end are the byte offsets of the word in the text
+	start, end int
+	// stop is set for stop words, which are not indexed but still take up a position
+	stop bool
+}
+
+// tokenize splits the text into words, i.e. runs of letters and digits, ignoring the apostrophes
+// within the words so that e.g. "don't" is a single word
+func tokenize(text string) []token {
+	var (
+		tokens []token
+		start  = -1
+	)
+	flush := func(end int) {
+		if start >= 0 {
+			word := foldWord(text[start:end])
+			tokens = append(tokens, token{term: stem(word), start: start, end: end, stop: stopWords[word]})
+			start = -1
+		}
+	}
+
+	for i, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			if start < 0 {
+				start = i
+			}
+		case (r == '\'' || r == '’') && start >= 0 && i+utf8.RuneLen(r) < len(text):
+			// Keep going if the apostrophe is followed by a letter, end the word otherwise
+			next, _ := utf8.DecodeRuneInString(text[i+utf8.RuneLen(r):])
+			if !unicode.IsLetter(next) {
+				flush(i)
+			}
+		default:
+			flush(i)
+		}
+	}
+	flush(len(text))
+	return tokens
+}
+
+// foldWord lowercases the word and strips its diacritics and apostrophes, e.g. "Clichéd" becomes "cliched"
+func foldWord(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.Is(unicode.Mn, r) || r == '\'' || r == '’' {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// stem reduces an English word to its stem by stripping its most common inflections, so that e.g.
+// "compiling", "compiled" and "compile" are indexed under the same term. It is a much lighter take on
+// the Porter stemmer, as the terms only need to be consistent, not to be actual words.
+func stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = word[:len(word)-1]
+	}
+
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed", "ly"} {
+		if stripped := strings.TrimSuffix(word, suffix); stripped != word && len(stripped) >= 3 && hasVowel(stripped) {
+			word = stripped
+			// "running" becomes "run" rather than "runn", but "falling" stays "fall"
+			if n := len(word); word[n-1] == word[n-2] && !strings.ContainsRune("aeiouylsz", rune(word[n-1])) {
+				word = word[:n-1]
+			}
+			break
+		}
+	}
+
+	if len(word) > 3 && strings.HasSuffix(word, "e") {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+// hasVowel returns whether the word contains a vowel
+func hasVowel(word string) bool {
+	return strings.ContainsAny(word, "aeiouy")
+}
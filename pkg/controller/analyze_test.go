@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type AnalyzeUnitSuite struct {
+	suite.Suite
+}
+
+func TestAnalyzeUnitSuite(t *testing.T) {
+	suite.Run(t, &AnalyzeUnitSuite{})
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *AnalyzeUnitSuite) TestTokenize() {
+	testCases := []struct {
+		name           string
+		input          string
+		expectedOutput []token
+	}{
+		{
+			"Punctuation And Stop Words",
+			"Is It Worth the Time?",
+			[]token{
+				{term: "is", start: 0, end: 2, stop: true},
+				{term: "it", start: 3, end: 5, stop: true},
+				{term: "worth", start: 6, end: 11},
+				{term: "the", start: 12, end: 15, stop: true},
+				{term: "tim", start: 16, end: 20},
+			},
+		},
+		{
+			"Apostrophes",
+			"Don't 'quote' me",
+			[]token{
+				{term: "dont", start: 0, end: 5},
+				{term: "quot", start: 7, end: 12},
+				{term: "me", start: 14, end: 16},
+			},
+		},
+		{
+			"Accents",
+			"Clichéd",
+			[]token{{term: "clich", start: 0, end: 8}},
+		},
+		{
+			"Empty",
+			"...",
+			nil,
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			us.Equal(test.expectedOutput, tokenize(test.input))
+		})
+	}
+}
+
+func (us *AnalyzeUnitSuite) TestStem() {
+	testCases := []struct {
+		inputs         []string
+		expectedOutput string
+	}{
+		{[]string{"compile", "compiles", "compiled", "compiling"}, "compil"},
+		{[]string{"comic", "comics"}, "comic"},
+		{[]string{"run", "running", "runs"}, "run"},
+		{[]string{"fall", "falling", "falls"}, "fall"},
+		{[]string{"story", "stories"}, "story"},
+		{[]string{"class", "classes"}, "class"},
+		{[]string{"bus"}, "bus"},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.expectedOutput, func() {
+			for _, input := range test.inputs {
+				us.Equal(test.expectedOutput, stem(input), input)
+			}
+		})
+	}
+}
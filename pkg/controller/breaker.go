@@ -0,0 +1,221 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of contacting the upstream server while the circuit breaker is open
+var ErrCircuitOpen = errors.New("upstream is unavailable, circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through while counting the failures
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single trial request through to decide whether to close or re-open
+	BreakerHalfOpen
+	// BreakerOpen rejects every request until the cool-down elapses
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops requests from reaching the upstream server once the ratio of failed requests
+// within a window exceeds a threshold, and lets them through again after a cool-down period
+type CircuitBreaker struct {
+	mu           sync.Mutex
+	state        BreakerState
+	failureRatio float64
+	minRequests  int
+	window       time.Duration
+	cooldown     time.Duration
+	// requests and failures are counted since windowStart while the breaker is closed
+	requests    int
+	failures    int
+	windowStart time.Time
+	openedAt    time.Time
+	probing     bool
+	now         func() time.Time
+}
+
+// NewCircuitBreaker returns a closed breaker that opens once at least 'failurePercent' percent of at
+// least 'minRequests' requests within 'window' failed, and that stays open for 'cooldown'
+func NewCircuitBreaker(failurePercent, minRequests int, window, cooldown time.Duration) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		failureRatio: float64(failurePercent) / 100,
+		minRequests:  minRequests,
+		window:       window,
+		cooldown:     cooldown,
+		now:          time.Now,
+	}
+	cb.windowStart = cb.now()
+	upstreamBreakerState.Set(float64(BreakerClosed))
+	return cb
+}
+
+// State returns the current state of the breaker
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == BreakerOpen && cb.now().Sub(cb.openedAt) >= cb.cooldown {
+		return BreakerHalfOpen
+	}
+	return cb.state
+}
+
+// Allow returns ErrCircuitOpen if a request may not be made at the moment. Every allowed request must
+// be followed by a call to Done with its outcome.
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		if cb.now().Sub(cb.openedAt) < cb.cooldown {
+			return ErrCircuitOpen
+		}
+		cb.setState(BreakerHalfOpen)
+		cb.probing = true
+	case BreakerHalfOpen:
+		if cb.probing {
+			return ErrCircuitOpen
+		}
+		cb.probing = true
+	case BreakerClosed:
+		if cb.window > 0 && cb.now().Sub(cb.windowStart) >= cb.window {
+			cb.resetWindow()
+		}
+	}
+	return nil
+}
+
+// Done records the outcome of a request that was allowed by the breaker
+func (cb *CircuitBreaker) Done(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	// A request cancelled by the client says nothing about the health of the upstream server
+	ignored := errors.Is(err, context.Canceled)
+	failed := !ignored && isUpstreamFailure(err)
+
+	switch cb.state {
+	case BreakerHalfOpen:
+		cb.probing = false
+		if ignored {
+			return
+		}
+		if failed {
+			cb.open()
+		} else {
+			cb.setState(BreakerClosed)
+			cb.resetWindow()
+		}
+	case BreakerClosed:
+		if ignored {
+			return
+		}
+		cb.requests++
+		if failed {
+			cb.failures++
+		}
+		if cb.requests >= cb.minRequests && float64(cb.failures) >= cb.failureRatio*float64(cb.requests) {
+			cb.open()
+		}
+	}
+}
+
+// open trips the breaker, the caller must hold the lock
+func (cb *CircuitBreaker) open() {
+	cb.setState(BreakerOpen)
+	cb.openedAt = cb.now()
+}
+
+// resetWindow starts a new counting window, the caller must hold the lock
+func (cb *CircuitBreaker) resetWindow() {
+	cb.requests, cb.failures = 0, 0
+	cb.windowStart = cb.now()
+}
+
+// setState changes the state of the breaker and exports it, the caller must hold the lock
+func (cb *CircuitBreaker) setState(state BreakerState) {
+	cb.state = state
+	upstreamBreakerState.Set(float64(state))
+}
+
+// isUpstreamFailure returns whether the error means that the upstream server is unhealthy, as opposed
+// to e.g. a comic that does not exist
+func isUpstreamFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrComicNotFound) || errors.Is(err, ErrImageNotFound) {
+		return false
+	}
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.StatusCode >= http.StatusInternalServerError ||
+			upstreamErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// BreakerSource is a ComicSource that guards another source with a CircuitBreaker
+type BreakerSource struct {
+	Source  ComicSource
+	Breaker *CircuitBreaker
+}
+
+// NewBreakerSource returns a pointer to a new BreakerSource instance
+func NewBreakerSource(source ComicSource, breaker *CircuitBreaker) *BreakerSource {
+	return &BreakerSource{
+		Source:  source,
+		Breaker: breaker,
+	}
+}
+
+// GetComic returns the comic with the given number from the guarded source, unless the breaker is open
+func (s *BreakerSource) GetComic(ctx context.Context, num int) (Comic, error) {
+	if err := s.Breaker.Allow(); err != nil {
+		return Comic{}, err
+	}
+	comic, err := s.Source.GetComic(ctx, num)
+	s.Breaker.Done(err)
+	return comic, err
+}
+
+// GetLatest returns the most recently published comic from the guarded source, unless the breaker is open
+func (s *BreakerSource) GetLatest(ctx context.Context) (Comic, error) {
+	if err := s.Breaker.Allow(); err != nil {
+		return Comic{}, err
+	}
+	comic, err := s.Source.GetLatest(ctx)
+	s.Breaker.Done(err)
+	return comic, err
+}
+
+// GetImage returns the image of the comic from the guarded source, unless the breaker is open or the
+// guarded source does not serve images
+func (s *BreakerSource) GetImage(ctx context.Context, comic Comic) ([]byte, error) {
+	source, ok := s.Source.(ImageSource)
+	if !ok {
+		return nil, errImagesUnsupported
+	}
+	if err := s.Breaker.Allow(); err != nil {
+		return nil, err
+	}
+	image, err := source.GetImage(ctx, comic)
+	s.Breaker.Done(err)
+	return image, err
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type BreakerUnitSuite struct {
+	suite.Suite
+	now     time.Time
+	breaker *CircuitBreaker
+}
+
+func TestBreakerUnitSuite(t *testing.T) {
+	suite.Run(t, &BreakerUnitSuite{})
+}
+
+func (us *BreakerUnitSuite) SetupTest() {
+	us.now = time.Now()
+	us.breaker = NewCircuitBreaker(50, 4, time.Minute, 30*time.Second)
+	us.breaker.now = func() time.Time { return us.now }
+}
+
+// request makes a request through the breaker that fails with the given error
+func (us *BreakerUnitSuite) request(err error) error {
+	if allowErr := us.breaker.Allow(); allowErr != nil {
+		return allowErr
+	}
+	us.breaker.Done(err)
+	return nil
+}
+
+// failingSource is a ComicSource whose upstream is always down
+type failingSource struct {
+	calls int
+}
+
+func (s *failingSource) GetComic(ctx context.Context, num int) (Comic, error) {
+	s.calls++
+	return Comic{}, &UpstreamError{StatusCode: http.StatusServiceUnavailable}
+}
+
+func (s *failingSource) GetLatest(ctx context.Context) (Comic, error) {
+	return s.GetComic(ctx, 0)
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *BreakerUnitSuite) TestOpensOnFailureRatio() {
+	failure := errors.New("connection refused")
+
+	// Below the minimum number of requests the breaker stays closed, even if they all failed
+	us.Nil(us.request(failure))
+	us.Nil(us.request(failure))
+	us.Nil(us.request(failure))
+	us.Equal(BreakerClosed, us.breaker.State())
+
+	// Below the failure ratio as well
+	us.now = us.now.Add(2 * time.Minute)
+	us.Nil(us.request(nil))
+	us.Nil(us.request(nil))
+	us.Nil(us.request(nil))
+	us.Nil(us.request(failure))
+	us.Nil(us.request(failure))
+	us.Equal(BreakerClosed, us.breaker.State())
+	us.Nil(us.request(failure))
+	us.Equal(BreakerOpen, us.breaker.State())
+	us.Equal(float64(BreakerOpen), testutil.ToFloat64(upstreamBreakerState))
+	us.ErrorIs(us.request(nil), ErrCircuitOpen)
+}
+
+func (us *BreakerUnitSuite) TestIgnoresHealthyUpstreamErrors() {
+	for i := 0; i < 10; i++ {
+		us.Nil(us.request(ErrComicNotFound))
+		us.Nil(us.request(&UpstreamError{StatusCode: http.StatusNotFound}))
+		us.Nil(us.request(context.Canceled))
+	}
+	us.Equal(BreakerClosed, us.breaker.State())
+}
+
+func (us *BreakerUnitSuite) TestWindowReset() {
+	failure := errors.New("connection refused")
+	us.Nil(us.request(failure))
+	us.Nil(us.request(failure))
+	us.Nil(us.request(failure))
+
+	// The failures of the previous window are forgotten
+	us.now = us.now.Add(2 * time.Minute)
+	us.Nil(us.request(failure))
+	us.Nil(us.request(nil))
+	us.Nil(us.request(nil))
+	us.Nil(us.request(nil))
+	us.Equal(BreakerClosed, us.breaker.State())
+}
+
+func (us *BreakerUnitSuite) TestHalfOpen() {
+	failure := errors.New("connection refused")
+	for i := 0; i < 4; i++ {
+		us.Nil(us.request(failure))
+	}
+	us.Equal(BreakerOpen, us.breaker.State())
+
+	// After the cool-down a single trial request is let through, and its failure re-opens the breaker
+	us.now = us.now.Add(time.Minute)
+	us.Equal(BreakerHalfOpen, us.breaker.State())
+	us.Nil(us.breaker.Allow())
+	us.ErrorIs(us.breaker.Allow(), ErrCircuitOpen)
+	us.breaker.Done(failure)
+	us.Equal(BreakerOpen, us.breaker.State())
+
+	// A successful trial request closes it
+	us.now = us.now.Add(time.Minute)
+	us.Nil(us.request(nil))
+	us.Equal(BreakerClosed, us.breaker.State())
+	us.Equal(float64(BreakerClosed), testutil.ToFloat64(upstreamBreakerState))
+}
+
+func (us *BreakerUnitSuite) TestDegradedResponse() {
+	source := &failingSource{}
+	breaker := NewCircuitBreaker(50, 1, time.Minute, time.Hour)
+	ctrl := NewController(&config.Config{FetchWorkers: 1, CacheSize: 10}, NewBreakerSource(source, breaker), nil)
+	ctrl.cache.Add(41, Comic{Num: 41, Title: "Old Drawing", Month: "1"})
+
+	router := gin.New()
+	router.GET("/comics", ctrl.GetComics)
+	router.GET("/ping", ctrl.Health)
+
+	// The first failure opens the breaker, which is a failure of the whole request
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/comics?start=40&end=40", nil))
+	us.Equal(http.StatusBadGateway, recorder.Code)
+
+	// From then on upstream is not contacted and the cached comics are served
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/comics?start=40&end=42", nil))
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal(1, source.calls)
+	us.Equal(degradedWarning, recorder.Header().Get("Warning"))
+	var response comicsResponse
+	us.Nil(json.Unmarshal(recorder.Body.Bytes(), &response))
+	us.True(response.Partial)
+	us.Equal([]Comic{{Num: 41, Title: "Old Drawing", Month: "1"}}, response.Comics)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal(`{"message":"pong","upstream":"open"}`, recorder.Body.String())
+}
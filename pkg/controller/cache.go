@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// lruCache is a bounded, in-memory LRU cache whose entries expire after a TTL. A nil *lruCache is a
+// valid, always empty cache.
+type lruCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[K]*list.Element
+	// order holds the entries from the most to the least recently used
+	order   *list.List
+	now     func() time.Time
+	metrics cacheMetrics
+}
+
+// lruEntry is a single element of the lruCache
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// cacheMetrics are the counters updated by a cache, any of which may be nil
+type cacheMetrics struct {
+	hits      prometheus.Counter
+	misses    prometheus.Counter
+	evictions prometheus.Counter
+}
+
+// inc increments the counter, if there is one
+func inc(counter prometheus.Counter) {
+	if counter != nil {
+		counter.Inc()
+	}
+}
+
+// newLRUCache returns a cache holding at most 'size' entries for 'ttl' each, or nil if the size is not
+// positive. A non-positive TTL means that the entries never expire.
+func newLRUCache[K comparable, V any](size int, ttl time.Duration, metrics cacheMetrics) *lruCache[K, V] {
+	if size <= 0 {
+		return nil
+	}
+	return &lruCache[K, V]{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[K]*list.Element, size),
+		order:   list.New(),
+		now:     time.Now,
+		metrics: metrics,
+	}
+}
+
+// newComicCache returns a cache holding at most 'size' comics by number for 'ttl' each, or nil if the
+// size is not positive. A non-positive TTL means that the entries never expire.
+func newComicCache(size int, ttl time.Duration) *lruCache[int, Comic] {
+	return newLRUCache[int, Comic](size, ttl, cacheMetrics{hits: cacheHits, misses: cacheMisses, evictions: cacheEvictions})
+}
+
+// Get returns the cached value with the given key, if it is present and has not expired
+func (lc *lruCache[K, V]) Get(key K) (V, bool) {
+	var zero V
+	if lc == nil {
+		return zero, false
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	elem, ok := lc.entries[key]
+	if !ok {
+		inc(lc.metrics.misses)
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[K, V])
+	if lc.ttl > 0 && lc.now().After(entry.expires) {
+		lc.remove(elem)
+		inc(lc.metrics.misses)
+		return zero, false
+	}
+	lc.order.MoveToFront(elem)
+	inc(lc.metrics.hits)
+	return entry.value, true
+}
+
+// Add inserts the value into the cache, evicting the least recently used one if the cache is full
+func (lc *lruCache[K, V]) Add(key K, value V) {
+	if lc == nil {
+		return
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	entry := &lruEntry[K, V]{key: key, value: value, expires: lc.now().Add(lc.ttl)}
+	if elem, ok := lc.entries[key]; ok {
+		elem.Value = entry
+		lc.order.MoveToFront(elem)
+		return
+	}
+	lc.entries[key] = lc.order.PushFront(entry)
+	if lc.order.Len() > lc.size {
+		lc.remove(lc.order.Back())
+	}
+}
+
+// Purge empties the cache and returns the number of entries that were removed
+func (lc *lruCache[K, V]) Purge() int {
+	if lc == nil {
+		return 0
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	count := lc.order.Len()
+	lc.entries = make(map[K]*list.Element, lc.size)
+	lc.order.Init()
+	return count
+}
+
+// Len returns the number of entries in the cache, including the expired ones that were not yet evicted
+func (lc *lruCache[K, V]) Len() int {
+	if lc == nil {
+		return 0
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.order.Len()
+}
+
+// remove evicts the given element, the caller must hold the lock
+func (lc *lruCache[K, V]) remove(elem *list.Element) {
+	lc.order.Remove(elem)
+	delete(lc.entries, elem.Value.(*lruEntry[K, V]).key)
+	inc(lc.metrics.evictions)
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type CacheUnitSuite struct {
+	suite.Suite
+}
+
+func TestCacheUnitSuite(t *testing.T) {
+	suite.Run(t, &CacheUnitSuite{})
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *CacheUnitSuite) TestDisabledCache() {
+	cache := newComicCache(0, time.Hour)
+	us.Nil(cache)
+
+	cache.Add(1, Comic{Num: 1})
+	_, ok := cache.Get(1)
+	us.False(ok)
+	us.Equal(0, cache.Len())
+	us.Equal(0, cache.Purge())
+}
+
+func (us *CacheUnitSuite) TestLeastRecentlyUsedEviction() {
+	cache := newComicCache(2, 0)
+	evictions := testutil.ToFloat64(cacheEvictions)
+
+	cache.Add(1, Comic{Num: 1})
+	cache.Add(2, Comic{Num: 2})
+	// Using the first comic makes the second one the least recently used
+	_, ok := cache.Get(1)
+	us.True(ok)
+	cache.Add(3, Comic{Num: 3})
+
+	_, ok = cache.Get(2)
+	us.False(ok)
+	comic, ok := cache.Get(1)
+	us.True(ok)
+	us.Equal(1, comic.Num)
+	_, ok = cache.Get(3)
+	us.True(ok)
+	us.Equal(2, cache.Len())
+	us.Equal(evictions+1, testutil.ToFloat64(cacheEvictions))
+}
+
+func (us *CacheUnitSuite) TestExpiry() {
+	now := time.Now()
+	cache := newComicCache(10, time.Minute)
+	cache.now = func() time.Time { return now }
+	hits, misses := testutil.ToFloat64(cacheHits), testutil.ToFloat64(cacheMisses)
+
+	cache.Add(1, Comic{Num: 1, Title: "Barrel - Part 1"})
+	_, ok := cache.Get(1)
+	us.True(ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.Get(1)
+	us.False(ok)
+	us.Equal(0, cache.Len())
+	us.Equal(hits+1, testutil.ToFloat64(cacheHits))
+	us.Equal(misses+1, testutil.ToFloat64(cacheMisses))
+}
+
+func (us *CacheUnitSuite) TestPurge() {
+	cache := newComicCache(10, 0)
+	cache.Add(1, Comic{Num: 1})
+	cache.Add(2, Comic{Num: 2})
+	// Adding an existing comic replaces it rather than inserting a new entry
+	cache.Add(2, Comic{Num: 2, Title: "Petit Trees (sheep)"})
+
+	comic, ok := cache.Get(2)
+	us.True(ok)
+	us.Equal("Petit Trees (sheep)", comic.Title)
+	us.Equal(2, cache.Purge())
+	us.Equal(0, cache.Len())
+}
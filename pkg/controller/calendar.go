@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mimeCalendar = "text/calendar"
+
+	// icsLineLength is the maximum length of a calendar line in octets, excluding the line break
+	icsLineLength = 75
+	// icsDate and icsDateTime are the formats of the calendar dates and UTC date-times
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405Z"
+)
+
+// icsEscaper escapes the characters that have a special meaning in calendar text values
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// GetCalendar is a gin handler function that returns the comics selected like the feeds are, see
+// queryFeed, as an RFC 5545 calendar with an all-day event on the publication date of every comic.
+// Comics without a valid publication date are left out.
+func (ctrl *Controller) GetCalendar(c *gin.Context) {
+	comics, ok := ctrl.queryFeed(c)
+	if !ok {
+		return
+	}
+
+	// The events are identified by the comic and the upstream they come from, so that they are
+	// recognized across requests
+	domain := "localhost"
+	if u, err := url.Parse(ctrl.Cfg.UpstreamURL); err == nil && u.Hostname() != "" {
+		domain = u.Hostname()
+	}
+	var cal icsWriter
+	cal.Line("BEGIN", "VCALENDAR")
+	cal.Line("VERSION", "2.0")
+	cal.Line("PRODID", "-//go-api-k8s//comics//EN")
+	cal.Line("CALSCALE", "GREGORIAN")
+	cal.Line("METHOD", "PUBLISH")
+	cal.Line("X-WR-CALNAME", icsEscaper.Replace(feedTitle))
+	for _, comic := range comics {
+		if comic.Published == nil {
+			continue
+		}
+		cal.Line("BEGIN", "VEVENT")
+		cal.Line("UID", fmt.Sprintf("comic-%d@%s", comic.Num, domain))
+		// The events are stamped with the publication date, so that the same comics always render the
+		// same calendar and can be revalidated
+		cal.Line("DTSTAMP", comic.Published.UTC().Format(icsDateTime))
+		cal.Line("DTSTART;VALUE=DATE", comic.Published.Format(icsDate))
+		cal.Line("DTEND;VALUE=DATE", comic.Published.AddDate(0, 0, 1).Format(icsDate))
+		cal.Line("SUMMARY", icsEscaper.Replace(fmt.Sprintf("#%d: %s", comic.Num, comic.Title)))
+		cal.Line("DESCRIPTION", icsEscaper.Replace(comic.Alt))
+		cal.Line("URL", ctrl.comicURL(comic))
+		cal.Line("TRANSP", "TRANSPARENT")
+		cal.Line("END", "VEVENT")
+	}
+	cal.Line("END", "VCALENDAR")
+
+	setLastModified(c, comics...)
+	c.Header("Content-Disposition", `inline; filename="comics.ics"`)
+	c.Data(http.StatusOK, mimeCalendar+"; charset=utf-8", cal.Bytes())
+}
+
+// icsWriter writes the content lines of a calendar, folding the ones that are too long
+type icsWriter struct {
+	bytes.Buffer
+}
+
+// Line writes a content line made of the given name, including its parameters, and escaped value.
+// Lines longer than icsLineLength octets are folded without splitting multi-byte characters.
+func (w *icsWriter) Line(name, value string) {
+	line := name + ":" + value
+	limit := icsLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of the continuation lines counts towards their length
+		limit = icsLineLength - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
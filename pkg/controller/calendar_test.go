@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type CalendarUnitSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func TestCalendarUnitSuite(t *testing.T) {
+	suite.Run(t, &CalendarUnitSuite{})
+}
+
+func (us *CalendarUnitSuite) SetupSuite() {
+	conf := config.NewConfig()
+	conf.UpstreamURL = "https://xkcd.com"
+	controller := NewController(conf, NewFSSource("testdata"), nil)
+
+	us.router = gin.New()
+	us.router.GET("/calendar.ics", controller.GetCalendar)
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *CalendarUnitSuite) TestCalendar() {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/calendar.ics?start=40&end=42&sort=num", nil)
+	us.Nil(err)
+	us.router.ServeHTTP(recorder, request)
+
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal("text/calendar; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.String()
+	us.True(strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	us.True(strings.HasSuffix(body, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	us.Equal(3, strings.Count(body, "BEGIN:VEVENT\r\n"))
+	us.Contains(body, "UID:comic-40@xkcd.com\r\n")
+	us.Contains(body, "DTSTAMP:20060101T000000Z\r\nDTSTART;VALUE=DATE:20060101\r\nDTEND;VALUE=DATE:20060102\r\n")
+	us.Contains(body, "SUMMARY:#41: Old Drawing\r\nDESCRIPTION:I don't want to talk about it\r\n")
+	us.Contains(body, "URL:https://xkcd.com/42/\r\n")
+
+	// Every line is within the limit, and the events are in the order of the comics
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n") {
+		us.LessOrEqual(len(line), icsLineLength)
+	}
+	us.Less(strings.Index(body, "comic-40@"), strings.Index(body, "comic-41@"))
+
+	// The calendar only depends on the comics
+	recorder = httptest.NewRecorder()
+	us.router.ServeHTTP(recorder, request)
+	us.Equal(body, recorder.Body.String())
+}
+
+func (us *CalendarUnitSuite) TestLine() {
+	testCases := []struct {
+		name           string
+		key            string
+		value          string
+		expectedOutput string
+	}{
+		{"Short Line", "SUMMARY", "Geico", "SUMMARY:Geico\r\n"},
+		{"Escaped Value", "DESCRIPTION", icsEscaper.Replace("a, b; c\\d\ne"), `DESCRIPTION:a\, b\; c\\d\ne` + "\r\n"},
+		{
+			"Folded Line",
+			"DESCRIPTION",
+			strings.Repeat("x", 150),
+			"DESCRIPTION:" + strings.Repeat("x", 63) + "\r\n " + strings.Repeat("x", 74) + "\r\n " + strings.Repeat("x", 13) + "\r\n",
+		},
+		{
+			"Multi-Byte Characters Are Not Split",
+			"SUMMARY",
+			strings.Repeat("é", 40),
+			"SUMMARY:" + strings.Repeat("é", 33) + "\r\n " + strings.Repeat("é", 7) + "\r\n",
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			var w icsWriter
+			w.Line(test.key, test.value)
+			us.Equal(test.expectedOutput, w.String())
+		})
+	}
+}
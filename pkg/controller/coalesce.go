@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/itsemre/go-api-k8s/pkg/logger"
+)
+
+// fetchGroup coalesces the concurrent fetches of the same comic, so that the requests asking for it at
+// the same time share a single call to the source. The zero value is ready to use.
+type fetchGroup struct {
+	mu    sync.Mutex
+	calls map[int]*fetchCall
+}
+
+// fetchCall is a fetch in flight, along with its result once it is done
+type fetchCall struct {
+	done  chan struct{}
+	comic Comic
+	err   error
+	// waiters is the number of callers still waiting for the result
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Do returns the result of the fetch of the comic with the given number, joining the fetch that is
+// already in flight if there is one. The fetch is detached from the context of the callers, so that it
+// goes on as long as one of them waits for it, and is only cancelled once all of them gave up. It still
+// logs with the entry of the caller that started it.
+func (g *fetchGroup) Do(ctx context.Context, num int, fetch fetchFunc) (Comic, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[int]*fetchCall)
+	}
+	call, ok := g.calls[num]
+	if ok {
+		call.waiters++
+		g.mu.Unlock()
+		coalescedFetches.Inc()
+	} else {
+		fetchCtx, cancel := context.WithCancel(log.NewContext(context.Background(), log.FromContext(ctx)))
+		call = &fetchCall{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.calls[num] = call
+		g.mu.Unlock()
+
+		go func() {
+			call.comic, call.err = fetch(fetchCtx, num)
+			g.forget(num, call)
+			cancel()
+			close(call.done)
+		}()
+	}
+
+	select {
+	case <-call.done:
+		return call.comic, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Nobody is interested in the result anymore, and the next callers must not join a cancelled fetch
+			call.cancel()
+			if g.calls[num] == call {
+				delete(g.calls, num)
+			}
+		}
+		g.mu.Unlock()
+		return Comic{}, ctx.Err()
+	}
+}
+
+// forget removes the fetch from the ones in flight, unless it was already replaced
+func (g *fetchGroup) forget(num int, call *fetchCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[num] == call {
+		delete(g.calls, num)
+	}
+}
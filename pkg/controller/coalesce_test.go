@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	log "github.com/itsemre/go-api-k8s/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type CoalesceUnitSuite struct {
+	suite.Suite
+}
+
+func TestCoalesceUnitSuite(t *testing.T) {
+	suite.Run(t, &CoalesceUnitSuite{})
+}
+
+// blockingSource is a ComicSource whose fetches block until they are released or cancelled
+type blockingSource struct {
+	calls     int32
+	release   chan struct{}
+	err       error
+	cancelled chan struct{}
+}
+
+func newBlockingSource(err error) *blockingSource {
+	return &blockingSource{release: make(chan struct{}), err: err, cancelled: make(chan struct{}, 1)}
+}
+
+func (s *blockingSource) GetComic(ctx context.Context, num int) (Comic, error) {
+	atomic.AddInt32(&s.calls, 1)
+	select {
+	case <-s.release:
+		return Comic{Num: num, Title: "Geico"}, s.err
+	case <-ctx.Done():
+		s.cancelled <- struct{}{}
+		return Comic{}, ctx.Err()
+	}
+}
+
+func (s *blockingSource) GetLatest(ctx context.Context) (Comic, error) {
+	return s.GetComic(ctx, 0)
+}
+
+// waitForWaiters waits until the given number of fetches joined the one in flight
+func (us *CoalesceUnitSuite) waitForWaiters(coalesced float64) {
+	us.Eventually(func() bool {
+		return testutil.ToFloat64(coalescedFetches) >= coalesced
+	}, time.Second, time.Millisecond)
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *CoalesceUnitSuite) TestConcurrentFetches() {
+	testCases := []struct {
+		name          string
+		err           error
+		expectedError error
+	}{
+		{"Success", nil, nil},
+		{"Error", ErrComicNotFound, ErrComicNotFound},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			source := newBlockingSource(test.err)
+			ctrl := NewController(&config.Config{}, source, nil)
+			coalesced := testutil.ToFloat64(coalescedFetches)
+
+			const callers = 5
+			var wg sync.WaitGroup
+			comics := make([]Comic, callers)
+			errs := make([]error, callers)
+			for j := 0; j < callers; j++ {
+				wg.Add(1)
+				go func(j int) {
+					defer wg.Done()
+					comics[j], errs[j] = ctrl.getComic(context.Background(), 42)
+				}(j)
+			}
+			us.waitForWaiters(coalesced + callers - 1)
+			close(source.release)
+			wg.Wait()
+
+			us.Equal(int32(1), atomic.LoadInt32(&source.calls))
+			us.Equal(coalesced+callers-1, testutil.ToFloat64(coalescedFetches))
+			for j := 0; j < callers; j++ {
+				us.Equal(test.expectedError, errs[j])
+				if test.expectedError == nil {
+					us.Equal(42, comics[j].Num)
+				}
+			}
+
+			// The fetches that follow a completed one are not coalesced with it
+			if test.expectedError != nil {
+				_, err := ctrl.getComic(context.Background(), 42)
+				us.Equal(test.expectedError, err)
+				us.Equal(int32(2), atomic.LoadInt32(&source.calls))
+			}
+		})
+	}
+}
+
+func (us *CoalesceUnitSuite) TestCancelledWaiter() {
+	var group fetchGroup
+	source := newBlockingSource(nil)
+	coalesced := testutil.ToFloat64(coalescedFetches)
+
+	// The first caller giving up does not cancel the fetch that the second one waits for
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := group.Do(ctx, 42, source.GetComic)
+		first <- err
+	}()
+	us.Eventually(func() bool { return atomic.LoadInt32(&source.calls) == 1 }, time.Second, time.Millisecond)
+	second := make(chan Comic, 1)
+	go func() {
+		comic, _ := group.Do(context.Background(), 42, source.GetComic)
+		second <- comic
+	}()
+	us.waitForWaiters(coalesced + 1)
+
+	cancel()
+	us.ErrorIs(<-first, context.Canceled)
+	close(source.release)
+	us.Equal(42, (<-second).Num)
+	us.Equal(int32(1), atomic.LoadInt32(&source.calls))
+	us.Empty(source.cancelled)
+}
+
+func (us *CoalesceUnitSuite) TestCancelledFetch() {
+	var group fetchGroup
+	source := newBlockingSource(nil)
+
+	// The fetch is cancelled once every caller gave up
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		_, err := group.Do(ctx, 42, source.GetComic)
+		result <- err
+	}()
+	us.Eventually(func() bool { return atomic.LoadInt32(&source.calls) == 1 }, time.Second, time.Millisecond)
+	cancel()
+	us.True(errors.Is(<-result, context.Canceled))
+	select {
+	case <-source.cancelled:
+	case <-time.After(time.Second):
+		us.Fail("the fetch was not cancelled")
+	}
+
+	// The next caller starts a new fetch instead of joining the cancelled one
+	close(source.release)
+	comic, err := group.Do(context.Background(), 42, source.GetComic)
+	us.Nil(err)
+	us.Equal(42, comic.Num)
+	us.Equal(int32(2), atomic.LoadInt32(&source.calls))
+}
+
+func (us *CoalesceUnitSuite) TestLogEntry() {
+	var group fetchGroup
+	entry := logrus.NewEntry(logrus.New()).WithField("request_id", "42")
+
+	// The detached fetch keeps the log entry of the caller that started it
+	_, err := group.Do(log.NewContext(context.Background(), entry), 42, func(ctx context.Context, num int) (Comic, error) {
+		us.Same(entry, log.FromContext(ctx))
+		return Comic{Num: num}, nil
+	})
+	us.Nil(err)
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/httputil"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// articles holds the leading articles that may be ignored when sorting titles, by language
+var articles = map[language.Base][]string{
+	language.MustParseBase("en"): {"the", "a", "an"},
+	language.MustParseBase("de"): {"der", "die", "das", "ein", "eine"},
+	language.MustParseBase("es"): {"el", "la", "los", "las", "un", "una"},
+	language.MustParseBase("fr"): {"le", "la", "les", "l'", "un", "une"},
+	language.MustParseBase("it"): {"il", "lo", "la", "i", "gli", "le", "l'", "un", "una"},
+	language.MustParseBase("nl"): {"de", "het", "een"},
+	language.MustParseBase("pt"): {"o", "a", "os", "as", "um", "uma"},
+}
+
+// titleCollator compares titles according to the rules of a locale
+type titleCollator struct {
+	collator       *collate.Collator
+	articles       []string
+	ignoreArticles bool
+}
+
+// parseCollator builds the titleCollator described by the request. The locale is taken from the
+// 'locale' query parameter, or the 'Accept-Language' header otherwise, and the leading articles of
+// the titles are ignored if the 'ignore_articles' query parameter is set.
+func parseCollator(c *gin.Context) (*titleCollator, error) {
+	tag := language.English
+	if locale := c.Query("locale"); locale != "" {
+		parsed, err := language.Parse(locale)
+		if err != nil {
+			return nil, errors.New("please make sure that 'locale' is a valid BCP 47 language tag")
+		}
+		tag = parsed
+	} else {
+		// The order of the titles depends on the header, which the caches must take into account
+		httputil.AddVary(c.Writer.Header(), "Accept-Language")
+		// An invalid header is not the fault of the query, so it falls back to the default instead
+		if tags, _, err := language.ParseAcceptLanguage(c.GetHeader("Accept-Language")); err == nil && len(tags) > 0 {
+			tag = tags[0]
+		}
+	}
+
+	ignoreArticles, _, err := getOptionalBool(c, "ignore_articles")
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := tag.Base()
+	return &titleCollator{
+		collator:       collate.New(tag, collate.Numeric),
+		articles:       articles[base],
+		ignoreArticles: ignoreArticles,
+	}, nil
+}
+
+// Compare returns the order of the two titles, based on their sort keys
+func (tc *titleCollator) Compare(a, b string) int {
+	return tc.collator.CompareString(tc.key(a), tc.key(b))
+}
+
+// key returns the part of the title that it is sorted by
+func (tc *titleCollator) key(title string) string {
+	key := titleSortKey(title)
+	if !tc.ignoreArticles {
+		return key
+	}
+	lower := strings.ToLower(key)
+	for _, article := range tc.articles {
+		// Elided articles such as "l'" are directly followed by the noun, the others by a space
+		if strings.HasPrefix(lower, article) && len(key) > len(article) &&
+			(strings.HasSuffix(article, "'") || key[len(article)] == ' ') {
+			if stripped := titleSortKey(key[len(article):]); stripped != "" {
+				return stripped
+			}
+		}
+	}
+	return key
+}
+
+// titleSortKey returns the NFC normalized title starting from its first letter, or from its first
+// digit if it has no letters, skipping e.g. the leading punctuation, symbols and spaces
+func titleSortKey(title string) string {
+	title = norm.NFC.String(title)
+	if key := findFirstAlphabeticalCharacter(title); key != "" {
+		return key
+	}
+	if i := strings.IndexFunc(title, unicode.IsDigit); i >= 0 {
+		return title[i:]
+	}
+	return strings.TrimSpace(title)
+}
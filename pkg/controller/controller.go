@@ -1,115 +1,468 @@
 package controller
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
+	"context"
+	"encoding/xml"
+	"errors"
+	"math/rand"
 	"net/http"
 	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/itsemre/go-api-k8s/pkg/config"
 )
 
+// statusClientClosedRequest is the non-standard status code used when the client disconnects
+// before the response could be written
+const statusClientClosedRequest = 499
+
+// degradedWarning is the 'Warning' header of the responses that are missing comics because upstream is unavailable
+const degradedWarning = `199 - "upstream is unavailable, the response is partial"`
+
+// maxRandomProbes is the maximum number of comics that are fetched in order to find a random one
+// matching the criteria
+const maxRandomProbes = 50
+
 // Controller is the struct implementing the corresponding gin.HandlerFunc fxns
 type Controller struct {
-	Cfg *config.Config
+	Cfg    *config.Config
+	Source ComicSource
+	Store  *ComicStore
+	Index  *SearchIndex
+	cache  *lruCache[int, Comic]
+	stats  *lruCache[string, comicStats]
+	images *imageCache
+	// fetches coalesces the concurrent fetches of the comics that are neither cached nor stored
+	fetches fetchGroup
 }
 
-// NewController returns a pointer to a new Controller instance
-func NewController(conf *config.Config) *Controller {
+// NewController returns a pointer to a new Controller instance that serves comics from the given store,
+// and retrieves the ones that are not in there from the given source. The store may be nil. Every comic
+// that goes through the controller is added to its search index, see LoadIndex for the stored ones.
+func NewController(conf *config.Config, source ComicSource, store *ComicStore) *Controller {
 	return &Controller{
-		Cfg: conf,
+		Cfg:    conf,
+		Source: source,
+		Store:  store,
+		Index:  NewSearchIndex(),
+		cache:  newComicCache(conf.CacheSize, time.Duration(conf.CacheTTL)*time.Second),
+		stats:  newStatsCache(statsCacheSize, time.Duration(conf.StatsCacheTTL)*time.Second),
+		images: newImageCache(conf.ImageCacheDir, int64(conf.ImageCacheSize)<<20),
+	}
+}
+
+// LoadIndex adds every comic of the store to the search index
+func (ctrl *Controller) LoadIndex() error {
+	comics, err := ctrl.Store.All()
+	if err != nil {
+		return err
 	}
+	ctrl.Index.Add(comics...)
+	return nil
+}
+
+// LoadImageCache prepares the directory of the image cache, and indexes the images it already contains
+func (ctrl *Controller) LoadImageCache() error {
+	return ctrl.images.Load()
+}
+
+// comicsResponse is the body of a successful response containing a list of comics
+type comicsResponse struct {
+	XMLName xml.Name `json:"-" xml:"comics" yaml:"-"`
+	Comics  []Comic  `json:"comics" xml:"comic" yaml:"comics"`
+	// Total is the number of comics matching the query, across every page
+	Total int `json:"total" xml:"total,attr" yaml:"total"`
+	// NextCursor is the cursor of the next page, if any
+	NextCursor string `json:"next_cursor,omitempty" xml:"next_cursor,attr,omitempty" yaml:"next_cursor,omitempty"`
+	// Missing lists the numbers in the range that do not exist upstream
+	Missing []int `json:"missing,omitempty" xml:"missing,omitempty" yaml:"missing,omitempty"`
+	// Partial is set when some of the comics could not be retrieved because upstream is unavailable
+	Partial bool `json:"partial,omitempty" xml:"partial,attr,omitempty" yaml:"partial,omitempty"`
 }
 
 // Comic is a representation of comic book metadata
 type Comic struct {
-	Num        int    `json:"num"`
-	Title      string `json:"title"`
-	SafeTitle  string `json:"safe_title"`
-	Day        string `json:"day"`
-	Month      string `json:"month"`
-	Year       string `json:"year"`
-	Transcript string `json:"transcript"`
-	Img        string `json:"img"`
-	Alt        string `json:"alt"`
-	News       string `json:"news"`
-	Link       string `json:"link"`
-}
-
-// GetComics a gin handler function that returns a list of comics that were published on
-// odd months, and whose number is in between the 'start' and 'end' query parameters, sorted
-// alphabetically by the title.
+	XMLName    xml.Name `json:"-" xml:"comic" yaml:"-"`
+	Num        int      `json:"num" xml:"num" yaml:"num"`
+	Title      string   `json:"title" xml:"title" yaml:"title"`
+	SafeTitle  string   `json:"safe_title" xml:"safe_title" yaml:"safe_title"`
+	Day        string   `json:"day" xml:"day" yaml:"day"`
+	Month      string   `json:"month" xml:"month" yaml:"month"`
+	Year       string   `json:"year" xml:"year" yaml:"year"`
+	Transcript string   `json:"transcript" xml:"transcript" yaml:"transcript"`
+	Img        string   `json:"img" xml:"img" yaml:"img"`
+	Alt        string   `json:"alt" xml:"alt" yaml:"alt"`
+	News       string   `json:"news" xml:"news" yaml:"news"`
+	Link       string   `json:"link" xml:"link" yaml:"link"`
+	// Published is the publication date parsed from Day, Month and Year, if they make a valid one
+	Published *Date `json:"published,omitempty" xml:"published,omitempty" yaml:"published,omitempty"`
+	// ParsedTranscript is the structured transcript, only set when it is asked for with 'expand=transcript'
+	ParsedTranscript *Transcript `json:"parsed_transcript,omitempty" xml:"parsed_transcript,omitempty" yaml:"parsed_transcript,omitempty"`
+}
+
+// GetComics a gin handler function that returns a list of comics whose number is in between the
+// 'start' and 'end' query parameters, selected as described by queryComics. Clients accepting newline
+// delimited JSON are streamed the comics as they are fetched instead, see streamNDJSON.
 func (ctrl *Controller) GetComics(c *gin.Context) {
-	var (
-		comics []Comic
-		comic  Comic
-	)
+	if acceptsNDJSON(c) {
+		ctrl.streamNDJSON(c)
+		return
+	}
+
+	// Extract query parameters
+	start, end, err := getStartEnd(c, ctrl.Cfg.MaxRangeSize)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	response, ok := ctrl.queryComics(c, start, end)
+	if !ok {
+		return
+	}
+	renderResponse(c, http.StatusOK, response)
+}
 
+// queryComics returns the page of comics whose number is in between start and end. The comics are
+// filtered by the query parameters parsed by parseFilters, which keep the ones published on odd months
+// by default, and sorted by the ones parsed by parseSort, which sort them alphabetically by the title by
+// default. Comics that do not exist are skipped and listed as missing, unless the 'strict' query
+// parameter is set, in which case they fail the request. The results are paginated as described by
+// parsePage, and the neighbouring pages are linked in the 'Link' header. The comics of the page come with
+// their structured transcript when 'expand=transcript' is given. The request is aborted, and false is
+// returned, if anything goes wrong.
+func (ctrl *Controller) queryComics(c *gin.Context, start, end int) (comicsResponse, bool) {
 	// Extract query parameters
-	start, end, err := getStartEnd(c)
+	strict, err := getStrict(c)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return comicsResponse{}, false
+	}
+	filters, err := parseFilters(c, monthParityOdd)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return comicsResponse{}, false
+	}
+	compare, err := parseSort(c)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return comicsResponse{}, false
+	}
+	// The clients that do not paginate get the whole range, as they did before the pages existed
+	maxPageSize := 0
+	if isPaginated(c) {
+		maxPageSize = ctrl.Cfg.MaxPageSize
+	}
+	pg, err := parsePage(c, maxPageSize)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return comicsResponse{}, false
+	}
+	expand, err := getExpandTranscript(c)
 	if err != nil {
 		AbortWithError(c, http.StatusBadRequest, err)
+		return comicsResponse{}, false
+	}
+
+	comics, rf, err := ctrl.fetchMatching(c.Request.Context(), start, end, strict, filters)
+	if err != nil {
+		abortWithFetchError(c, err)
+		return comicsResponse{}, false
+	}
+
+	sortComics(comics, compare)
+
+	// Only the comics of the page are expanded
+	page := pg.Apply(comics)
+	if expand {
+		for i := range page {
+			page[i] = expandTranscript(page[i])
+		}
+	}
+	ctrl.proxyImages(c, page)
+
+	response := comicsResponse{
+		Comics:     page,
+		Total:      len(comics),
+		NextCursor: pg.Next(len(comics)),
+		Missing:    rf.Missing(),
+	}
+	if links := pg.Links(c.Request.URL, len(comics)); links != "" {
+		c.Header("Link", links)
+	}
+	if rf.Partial() {
+		response.Partial = true
+		c.Header("Warning", degradedWarning)
+	}
+	return response, true
+}
+
+// fetchMatching fetches the whole range concurrently, and returns the comics matching the filters in
+// ascending order along with the rangeFetch that retrieved them. The fetch is cancelled along with the
+// context, i.e. when the client disconnects.
+func (ctrl *Controller) fetchMatching(ctx context.Context, start, end int, strict bool, filters []comicFilter) ([]Comic, *rangeFetch, error) {
+	rf := &rangeFetch{ctrl: ctrl, strict: strict}
+	fetched, err := fetchComics(ctx, start, end, ctrl.Cfg.FetchWorkers, rf.Fetch)
+	if err != nil {
+		return nil, rf, err
+	}
+
+	var comics []Comic
+	for _, comic := range fetched {
+		if matchesFilters(comic, filters) {
+			comics = append(comics, comic)
+		}
+	}
+	return comics, rf, nil
+}
+
+// GetComic is a gin handler function that returns the comic whose number is given in the path
+func (ctrl *Controller) GetComic(c *gin.Context) {
+	num, err := strconv.Atoi(c.Param("num"))
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, errors.New("please make sure that the comic number is an integer"))
+		return
+	}
+
+	comic, err := ctrl.getComic(c.Request.Context(), num)
+	if err != nil {
+		abortWithFetchError(c, err)
+		return
+	}
+	renderResponse(c, http.StatusOK, ctrl.proxyImage(c, comic))
+}
+
+// GetLatestComic is a gin handler function that returns the most recently published comic
+func (ctrl *Controller) GetLatestComic(c *gin.Context) {
+	comic, err := ctrl.getLatestComic(c.Request.Context())
+	if err != nil {
+		abortWithFetchError(c, err)
 		return
 	}
+	renderResponse(c, http.StatusOK, ctrl.proxyImage(c, comic))
+}
+
+// GetRandomComic is a gin handler function that returns a random comic, optionally constrained to
+// the range given by the 'start' and 'end' query parameters, and to the filters parsed by parseFilters
+// which keep the comics of any month by default
+func (ctrl *Controller) GetRandomComic(c *gin.Context) {
+	ctx := c.Request.Context()
 
-	client := &http.Client{}
+	filters, err := parseFilters(c, monthParityAny)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
 
-	// Iterate through the comics range
-	for i := start; i <= end; i++ {
-		// Get the metadata of the current comic book
-		req, err := http.NewRequest("GET", fmt.Sprintf("https://xkcd.com/%d/info.0.json", i), nil)
+	// Without a range, pick among every published comic
+	var start, end int
+	if !c.Request.URL.Query().Has("start") && !c.Request.URL.Query().Has("end") {
+		latest, err := ctrl.getLatestComic(ctx)
 		if err != nil {
-			AbortWithError(c, http.StatusInternalServerError, err)
+			abortWithFetchError(c, err)
 			return
 		}
-		resp, err := client.Do(req)
-		if err != nil {
-			AbortWithError(c, http.StatusInternalServerError, err)
+		start, end = 1, latest.Num
+	} else if start, end, err = getStartEnd(c, ctrl.Cfg.MaxRangeSize); err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+	if end < start {
+		AbortWithError(c, http.StatusBadRequest, errors.New("please make sure that the starting number is not greater than the ending number"))
+		return
+	}
+
+	// Probe the range in a random order until a comic matches, skipping the ones that are missing
+	unavailable := false
+	for _, offset := range randomOffsets(end-start+1, maxRandomProbes) {
+		comic, err := ctrl.getComic(ctx, start+offset)
+		switch {
+		case errors.Is(err, ErrComicNotFound):
+			continue
+		case errors.Is(err, ErrCircuitOpen):
+			unavailable = true
+			continue
+		case err != nil:
+			abortWithFetchError(c, err)
 			return
 		}
-		defer resp.Body.Close()
-		bodyText, err := io.ReadAll(resp.Body)
-		if err != nil {
-			AbortWithError(c, http.StatusInternalServerError, err)
+		if matchesFilters(comic, filters) {
+			// Every request picks another comic, so that the response must never be reused
+			c.Header("Cache-Control", "no-store")
+			renderResponse(c, http.StatusOK, ctrl.proxyImage(c, comic))
 			return
 		}
-		err = json.Unmarshal(bodyText, &comic)
+	}
+
+	if unavailable {
+		abortWithFetchError(c, ErrCircuitOpen)
+		return
+	}
+	AbortWithError(c, http.StatusNotFound, errors.New("no comic matching the criteria was found"))
+}
+
+// GetComicsOnThisDay is a gin handler function that returns every comic published on the calendar day
+// given by the 'date' query parameter, formatted as MM-DD and defaulting to today, across all years. The
+// comics are sorted by their number, i.e. chronologically. Since the number of a comic says nothing about
+// its date, every comic has to be looked at, hence they are looked up in the store rather than upstream.
+func (ctrl *Controller) GetComicsOnThisDay(c *gin.Context) {
+	if ctrl.Store == nil {
+		AbortWithError(c, http.StatusServiceUnavailable, errors.New("the comics can only be looked up by date when the comic store is enabled"))
+		return
+	}
+
+	day := time.Now().UTC()
+	if value := c.Query("date"); value != "" {
+		// Any leap year will do, so that February 29th is a valid day
+		parsed, err := time.Parse(dateLayout, "2000-"+value)
 		if err != nil {
-			AbortWithError(c, http.StatusInternalServerError, err)
+			AbortWithError(c, http.StatusBadRequest, errors.New("please make sure that 'date' is formatted as MM-DD"))
 			return
 		}
+		day = parsed
+	}
+
+	stored, err := ctrl.Store.All()
+	if err != nil {
+		AbortWithError(c, http.StatusInternalServerError, err)
+		return
+	}
 
-		// If the published month is odd, add the comic to the list
-		if m, _ := strconv.Atoi(comic.Month); m%2 != 0 {
+	comics := []Comic{}
+	for _, comic := range stored {
+		if comic.Published != nil && comic.Published.Month() == day.Month() && comic.Published.Day() == day.Day() {
 			comics = append(comics, comic)
 		}
 	}
 
-	// Sort list alphabetically by the title
-	sort.Slice(comics, func(i, j int) bool {
-		title1 := comics[i].Title
-		title2 := comics[j].Title
+	ctrl.proxyImages(c, comics)
+	renderResponse(c, http.StatusOK, comicsResponse{Comics: comics, Total: len(comics)})
+}
 
-		// If the first character is non-alphabetic get the first alphabetic character
-		if !isAlphabetical(title1[0]) {
-			title1 = findFirstAlphabeticalCharacter(title1)
-		}
-		if !isAlphabetical(title2[0]) {
-			title2 = findFirstAlphabeticalCharacter(title2)
+// PurgeCache is a gin handler function that empties the in-memory comic cache, along with the cached
+// statistics that were computed from it
+func (ctrl *Controller) PurgeCache(c *gin.Context) {
+	ctrl.stats.Purge()
+	renderResponse(c, http.StatusOK, gin.H{"purged": ctrl.cache.Purge()})
+}
+
+// getComic returns the comic with the given number from the cache or the store if possible, and from
+// the source otherwise, in which case the comic is also persisted in the store. Concurrent fetches of
+// the same comic from the source are coalesced into one. The comics that are not cached yet are added
+// to the search index.
+func (ctrl *Controller) getComic(ctx context.Context, num int) (Comic, error) {
+	if comic, ok := ctrl.cache.Get(num); ok {
+		return comic, nil
+	}
+
+	comic, found, err := ctrl.Store.Get(num)
+	if err != nil {
+		return comic, err
+	}
+	if !found {
+		comic, err = ctrl.fetches.Do(ctx, num, func(ctx context.Context, num int) (Comic, error) {
+			comic, err := ctrl.Source.GetComic(ctx, num)
+			if err != nil {
+				return comic, err
+			}
+			return comic, ctrl.Store.Put(comic)
+		})
+		if err != nil {
+			return comic, err
 		}
+	}
 
-		return title1 < title2
-	})
+	ctrl.Index.Add(comic)
+	ctrl.cache.Add(comic.Num, comic)
+	return comic, nil
+}
+
+// getLatestComic returns the most recently published comic from the source, and persists and indexes it
+func (ctrl *Controller) getLatestComic(ctx context.Context) (Comic, error) {
+	comic, err := ctrl.Source.GetLatest(ctx)
+	if err != nil {
+		return comic, err
+	}
+	if err = ctrl.Store.Put(comic); err != nil {
+		return comic, err
+	}
+	ctrl.Index.Add(comic)
+	ctrl.cache.Add(comic.Num, comic)
+	return comic, nil
+}
 
-	c.JSON(http.StatusOK, gin.H{"comics": comics})
+// randomOffsets returns at most 'count' distinct offsets in between 0 and 'size' (exclusive), in a random
+// order, without allocating anything proportional to the size
+func randomOffsets(size, count int) []int {
+	if size <= count {
+		return rand.Perm(size)
+	}
+	offsets := make([]int, 0, count)
+	picked := make(map[int]bool, count)
+	for len(offsets) < count {
+		if offset := rand.Intn(size); !picked[offset] {
+			picked[offset] = true
+			offsets = append(offsets, offset)
+		}
+	}
+	return offsets
 }
 
-// Health is a simple handler that allows us to check the status of our API
+// Health is a simple handler that allows us to check the status of our API, along with the state of
+// the upstream circuit breaker if there is one. An open breaker does not make the API unhealthy, as it
+// keeps serving what is available locally.
 func (ctrl *Controller) Health(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	body := gin.H{
 		"message": "pong",
-	})
+	}
+	if source, ok := ctrl.Source.(*BreakerSource); ok {
+		body["upstream"] = source.Breaker.State().String()
+	}
+	renderResponse(c, http.StatusOK, body)
+}
+
+// rangeFetch fetches the comics of a range on behalf of a single request. Unless strict, the missing
+// comics are skipped and, while the upstream circuit breaker is open, only the ones that are available
+// locally are served.
+type rangeFetch struct {
+	ctrl        *Controller
+	strict      bool
+	mu          sync.Mutex
+	missing     []int
+	unavailable int32
+}
+
+// Fetch is the fetchFunc retrieving a comic of the range
+func (rf *rangeFetch) Fetch(ctx context.Context, num int) (Comic, error) {
+	comic, err := rf.ctrl.getComic(ctx, num)
+	switch {
+	case err == nil || rf.strict:
+		return comic, err
+	case errors.Is(err, ErrComicNotFound):
+		rf.mu.Lock()
+		rf.missing = append(rf.missing, num)
+		rf.mu.Unlock()
+		return comic, errSkipComic
+	case errors.Is(err, ErrCircuitOpen):
+		atomic.AddInt32(&rf.unavailable, 1)
+		return comic, errSkipComic
+	}
+	return comic, err
+}
+
+// Missing returns the numbers of the comics that do not exist, in ascending order
+func (rf *rangeFetch) Missing() []int {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	sort.Ints(rf.missing)
+	return rf.missing
+}
+
+// Partial returns whether some comics were left out because upstream is unavailable
+func (rf *rangeFetch) Partial() bool {
+	return atomic.LoadInt32(&rf.unavailable) > 0
 }
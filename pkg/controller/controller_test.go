@@ -1,11 +1,13 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/itsemre/go-api-k8s/pkg/config"
@@ -35,7 +37,10 @@ type ControllerUnitSuite struct {
 
 func (us *ControllerUnitSuite) SetupSuite() {
 	cfg = config.NewConfig()
-	controller := NewController(cfg)
+	cfg.FetchWorkers = 4
+	cfg.CacheSize = 100
+	cfg.MaxRangeSize = 1000
+	controller := NewController(cfg, NewFSSource("testdata"), nil)
 	us.ctrl = controller
 
 	router := gin.Default()
@@ -53,6 +58,22 @@ func (us *ControllerUnitSuite) SetupSuite() {
 		"/comics",
 		controller.GetComics,
 	)
+	us.server.Router.GET(
+		"/comics/latest",
+		controller.GetLatestComic,
+	)
+	us.server.Router.GET(
+		"/comics/random",
+		controller.GetRandomComic,
+	)
+	us.server.Router.GET(
+		"/comics/:num",
+		controller.GetComic,
+	)
+	us.server.Router.DELETE(
+		"/admin/cache",
+		controller.PurgeCache,
+	)
 }
 
 func TestControllerUnitSuite(t *testing.T) {
@@ -64,6 +85,7 @@ func TestControllerUnitSuite(t *testing.T) {
 // =============================================================================
 
 func (us *ControllerUnitSuite) TestComicsEndpoint() {
+	published := NewDate(2006, time.January, 1)
 	testCases := []struct {
 		name             string
 		query            string
@@ -76,7 +98,9 @@ func (us *ControllerUnitSuite) TestComicsEndpoint() {
 			200,
 			struct {
 				Comics []Comic `json:"comics"`
+				Total  int     `json:"total"`
 			}{
+				Total: 3,
 				Comics: []Comic{
 					{
 						Num:        42,
@@ -90,6 +114,7 @@ func (us *ControllerUnitSuite) TestComicsEndpoint() {
 						Alt:        "David did this",
 						News:       "",
 						Link:       "",
+						Published:  &published,
 					},
 					{
 						Num:        40,
@@ -103,6 +128,7 @@ func (us *ControllerUnitSuite) TestComicsEndpoint() {
 						Alt:        "Like a beacon",
 						News:       "",
 						Link:       "",
+						Published:  &published,
 					},
 					{
 						Num:        41,
@@ -116,6 +142,7 @@ func (us *ControllerUnitSuite) TestComicsEndpoint() {
 						Alt:        "I don't want to talk about it",
 						News:       "",
 						Link:       "",
+						Published:  &published,
 					},
 				},
 			},
@@ -160,6 +187,22 @@ func (us *ControllerUnitSuite) TestComicsEndpoint() {
 				Msg: "please make sure that the ending number is an integer",
 			},
 		},
+		{
+			"Range Too Large",
+			"?start=1&end=4000000000",
+			400,
+			&errorBody{
+				Msg: "please make sure that the range holds at most 1000 comics",
+			},
+		},
+		{
+			"Range Wrapping Around",
+			"?start=-9223372036854775807&end=9223372036854775807",
+			400,
+			&errorBody{
+				Msg: "please make sure that the range holds at most 1000 comics",
+			},
+		},
 	}
 
 	for i := range testCases {
@@ -183,3 +226,419 @@ func (us *ControllerUnitSuite) TestComicsEndpoint() {
 		})
 	}
 }
+
+// =============================================================================
+// CACHE ENDPOINT TEST
+// =============================================================================
+
+func (us *ControllerUnitSuite) TestPurgeCacheEndpoint() {
+	// Warm the cache up with a range of comics
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/comics?start=40&end=42", nil)
+	us.Nil(err)
+	us.server.Router.ServeHTTP(recorder, request)
+	us.Equal(http.StatusOK, recorder.Code)
+
+	for _, expected := range []string{`{"purged":3}`, `{"purged":0}`} {
+		recorder = httptest.NewRecorder()
+		request, err = http.NewRequest(http.MethodDelete, "/admin/cache", nil)
+		us.Nil(err)
+		us.server.Router.ServeHTTP(recorder, request)
+		us.Equal(http.StatusOK, recorder.Code)
+		us.Equal(expected, recorder.Body.String())
+	}
+}
+
+// =============================================================================
+// UPSTREAM ERRORS TEST
+// =============================================================================
+
+func (us *ControllerUnitSuite) TestUpstreamErrors() {
+	// The fake upstream knows every comic but #404, and is broken for #500
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var num int
+		if _, err := fmt.Sscanf(r.URL.Path, "/%d/info.0.json", &num); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch num {
+		case 404:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<html>404 Not Found</html>")
+		case 500:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprintf(w, `{"num": %d, "title": "Comic %d", "month": "1"}`, num, num)
+		}
+	}))
+	defer upstream.Close()
+
+	conf := config.NewConfig()
+	conf.FetchWorkers = 2
+	conf.UpstreamURL = upstream.URL
+	controller := NewController(conf, NewHTTPSource(conf), nil)
+	router := gin.New()
+	router.GET("/comics", controller.GetComics)
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"Missing Comic Skipped",
+			"?start=403&end=405",
+			http.StatusOK,
+			`{"comics":[{"num":403,"title":"Comic 403","safe_title":"","day":"","month":"1","year":"","transcript":"","img":"","alt":"","news":"","link":""},{"num":405,"title":"Comic 405","safe_title":"","day":"","month":"1","year":"","transcript":"","img":"","alt":"","news":"","link":""}],"total":2,"missing":[404]}`,
+		},
+		{
+			"Missing Comic Strict",
+			"?start=403&end=405&strict=true",
+			http.StatusNotFound,
+			fmt.Sprintf(`{"error":"upstream responded to %s/404/info.0.json with status 404"}`, upstream.URL),
+		},
+		{
+			"Upstream Failure",
+			"?start=499&end=501",
+			http.StatusBadGateway,
+			fmt.Sprintf(`{"error":"upstream responded to %s/500/info.0.json with status 503","upstream_status":503}`, upstream.URL),
+		},
+		{
+			"Invalid Strict Parameter",
+			"?start=1&end=2&strict=maybe",
+			http.StatusBadRequest,
+			`{"error":"please make sure that 'strict' is either true or false"}`,
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/comics%s", test.query), nil)
+			us.Nil(err)
+
+			router.ServeHTTP(recorder, request)
+			us.Equal(test.expectedStatus, recorder.Code)
+			us.Equal(test.expectedBody, recorder.Body.String())
+		})
+	}
+
+	// Upstream being unreachable is a gateway failure too
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	conf.UpstreamURL = unreachable.URL
+	controller = NewController(conf, NewHTTPSource(conf), nil)
+	router = gin.New()
+	router.GET("/comics/:num", controller.GetComic)
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/comics/42", nil)
+	us.Nil(err)
+	router.ServeHTTP(recorder, request)
+	us.Equal(http.StatusBadGateway, recorder.Code)
+	us.Contains(recorder.Body.String(), unreachable.URL+"/42/info.0.json")
+}
+
+// =============================================================================
+// SINGLE COMIC ENDPOINTS TEST
+// =============================================================================
+
+func (us *ControllerUnitSuite) TestSingleComicEndpoints() {
+	published := NewDate(2006, time.January, 1)
+	geico := Comic{
+		Num:        42,
+		Title:      "Geico",
+		SafeTitle:  "Geico",
+		Day:        "1",
+		Month:      "1",
+		Year:       "2006",
+		Transcript: "I just saved a bunch of money on my car insurance by threatening my agent with a golf club.\n{{title text: David did this}}",
+		Img:        "https://imgs.xkcd.com/comics/geico.jpg",
+		Alt:        "David did this",
+		Published:  &published,
+	}
+
+	testCases := []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		{
+			"Comic By Number",
+			"/comics/42",
+			200,
+			geico,
+		},
+		{
+			"Latest Comic",
+			"/comics/latest",
+			200,
+			geico,
+		},
+		{
+			"Random Comic In A Single Comic Range",
+			"/comics/random?start=42&end=42&month_parity=odd",
+			200,
+			geico,
+		},
+		{
+			"Missing Comic",
+			"/comics/404",
+			404,
+			&errorBody{
+				Msg: "testdata/404/info.0.json: comic not found",
+			},
+		},
+		{
+			"Invalid Comic Number",
+			"/comics/forty-two",
+			400,
+			&errorBody{
+				Msg: "please make sure that the comic number is an integer",
+			},
+		},
+		{
+			"Random Comic Without A Match",
+			"/comics/random?start=40&end=42&month_parity=even",
+			404,
+			&errorBody{
+				Msg: "no comic matching the criteria was found",
+			},
+		},
+		{
+			"Random Comic With An Invalid Parity",
+			"/comics/random?month_parity=prime",
+			400,
+			&errorBody{
+				Msg: "please make sure that 'month_parity' is one of 'odd', 'even' or 'any'",
+			},
+		},
+		{
+			"Random Comic In A Range Too Large",
+			"/comics/random?start=1&end=8000000000",
+			400,
+			&errorBody{
+				Msg: "please make sure that the range holds at most 1000 comics",
+			},
+		},
+		{
+			"Random Comic With An Inverted Range",
+			"/comics/random?start=42&end=40",
+			400,
+			&errorBody{
+				Msg: "please make sure that the starting number is not greater than the ending number",
+			},
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, test.path, nil)
+			us.Nil(err)
+
+			us.server.Router.ServeHTTP(recorder, request)
+			us.Equal(test.expectedStatus, recorder.Code)
+
+			expectedBytes, err := json.Marshal(test.expectedResponse)
+			us.Nil(err)
+			us.Equal(expectedBytes, recorder.Body.Bytes())
+		})
+	}
+
+	// Without a range, the random comic is one of the published ones
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/comics/random", nil)
+	us.Nil(err)
+	us.server.Router.ServeHTTP(recorder, request)
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal("no-store", recorder.Header().Get("Cache-Control"))
+	var comic Comic
+	us.Nil(json.Unmarshal(recorder.Body.Bytes(), &comic))
+	us.Contains([]int{40, 41, 42}, comic.Num)
+}
+
+func (us *ControllerUnitSuite) TestRandomOffsets() {
+	testCases := []struct {
+		name          string
+		size          int
+		count         int
+		expectedCount int
+	}{
+		{"Empty Range", 0, 50, 0},
+		{"Small Range", 3, 50, 3},
+		{"Large Range", 8000000000, 50, 50},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			offsets := randomOffsets(test.size, test.count)
+			us.Len(offsets, test.expectedCount)
+			seen := make(map[int]bool)
+			for _, offset := range offsets {
+				us.GreaterOrEqual(offset, 0)
+				us.Less(offset, test.size)
+				us.False(seen[offset], "the offsets should be distinct")
+				seen[offset] = true
+			}
+		})
+	}
+}
+
+// =============================================================================
+// PAGINATION TEST
+// =============================================================================
+
+func (us *ControllerUnitSuite) TestComicsPagination() {
+	// Walk the range one comic at a time, following the cursors
+	var nums []int
+	path := "/comics?start=40&end=42&limit=1"
+	for i := 0; path != ""; i++ {
+		us.Require().Less(i, 3, "the pages should end after the last comic")
+
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, path, nil)
+		us.Nil(err)
+		us.server.Router.ServeHTTP(recorder, request)
+		us.Require().Equal(http.StatusOK, recorder.Code)
+
+		var response comicsResponse
+		us.Nil(json.Unmarshal(recorder.Body.Bytes(), &response))
+		us.Equal(3, response.Total)
+		us.Len(response.Comics, 1)
+		nums = append(nums, response.Comics[0].Num)
+
+		path = ""
+		if response.NextCursor != "" {
+			path = "/comics?cursor=" + response.NextCursor + "&end=42&limit=1&start=40"
+			us.Contains(recorder.Header().Get("Link"), "<"+path+`>; rel="next"`)
+		} else {
+			us.NotContains(recorder.Header().Get("Link"), `rel="next"`)
+		}
+	}
+	us.Equal([]int{42, 40, 41}, nums)
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"Offset Past The End",
+			"?start=40&end=42&offset=5",
+			200,
+			`{"comics":[],"total":3}`,
+		},
+		{
+			"Cursor Of Another Query",
+			"?start=40&end=41&cursor=" + page{query: queryFingerprint(map[string][]string{"start": {"40"}, "end": {"42"}})}.encodeCursor(1),
+			400,
+			`{"error":"please make sure that 'cursor' is a value returned by a previous request with the same parameters"}`,
+		},
+		{
+			"Offset And Cursor",
+			"?start=40&end=42&offset=1&cursor=abc",
+			400,
+			`{"error":"please use either 'offset' or 'cursor', not both"}`,
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/comics"+test.query, nil)
+			us.Nil(err)
+
+			us.server.Router.ServeHTTP(recorder, request)
+			us.Equal(test.expectedStatus, recorder.Code)
+			us.Equal(test.expectedBody, recorder.Body.String())
+		})
+	}
+
+	// The maximum page size only applies to the requests that paginate
+	cfg.MaxPageSize = 2
+	defer func() { cfg.MaxPageSize = 0 }()
+	for query, expectedCount := range map[string]int{"?start=40&end=42": 3, "?start=40&end=42&offset=0": 2} {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/comics"+query, nil)
+		us.Nil(err)
+		us.server.Router.ServeHTTP(recorder, request)
+		us.Equal(http.StatusOK, recorder.Code)
+
+		var response comicsResponse
+		us.Nil(json.Unmarshal(recorder.Body.Bytes(), &response))
+		us.Len(response.Comics, expectedCount, query)
+		us.Equal(3, response.Total)
+	}
+}
+
+// =============================================================================
+// ON THIS DAY ENDPOINT TEST
+// =============================================================================
+
+func (us *ControllerUnitSuite) TestOnThisDayEndpoint() {
+	source := NewFSSource("testdata")
+	store, err := OpenComicStore(us.T().TempDir())
+	us.Require().Nil(err)
+	defer store.Close()
+	for num := 40; num <= 42; num++ {
+		comic, err := source.GetComic(context.Background(), num)
+		us.Require().Nil(err)
+		us.Require().Nil(store.Put(comic))
+	}
+	router := gin.New()
+	router.GET("/comics/on-this-day", NewController(cfg, source, store).GetComicsOnThisDay)
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedNums   []int
+	}{
+		{"Same Day", "?date=01-01", 200, []int{40, 41, 42}},
+		{"Other Day", "?date=02-29", 200, []int{}},
+		{"Invalid Date", "?date=02-30", 400, nil},
+		{"Full Date", "?date=2006-01-01", 400, nil},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/comics/on-this-day"+test.query, nil)
+			us.Nil(err)
+
+			router.ServeHTTP(recorder, request)
+			us.Equal(test.expectedStatus, recorder.Code)
+			if test.expectedNums == nil {
+				us.Equal(`{"error":"please make sure that 'date' is formatted as MM-DD"}`, recorder.Body.String())
+				return
+			}
+
+			var response comicsResponse
+			us.Nil(json.Unmarshal(recorder.Body.Bytes(), &response))
+			nums := []int{}
+			for _, comic := range response.Comics {
+				nums = append(nums, comic.Num)
+			}
+			us.Equal(test.expectedNums, nums)
+			us.Equal(len(test.expectedNums), response.Total)
+		})
+	}
+
+	// Without a store, the whole archive would have to be fetched from upstream
+	router = gin.New()
+	router.GET("/comics/on-this-day", NewController(cfg, source, nil).GetComicsOnThisDay)
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/comics/on-this-day?date=01-01", nil)
+	us.Require().Nil(err)
+	router.ServeHTTP(recorder, request)
+	us.Equal(http.StatusServiceUnavailable, recorder.Code)
+	us.Equal(`{"error":"the comics can only be looked up by date when the comic store is enabled"}`, recorder.Body.String())
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// dateLayout is the layout of an RFC 3339 full-date
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day, represented as an RFC 3339 full-date, e.g. 2006-01-02
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the date of the given day, in UTC
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate parses an RFC 3339 full-date
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(dateLayout, value)
+	return Date{t}, err
+}
+
+// String returns the date as an RFC 3339 full-date
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+// MarshalText implements encoding.TextMarshaler, which is used by the XML and YAML encoders
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(value))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Date) UnmarshalText(text []byte) error {
+	parsed, err := ParseDate(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// parseComic decodes the metadata of a comic as it is ingested, parsing its publication date once and
+// for all. Comics whose date is not valid are kept, without a publication date.
+func parseComic(body []byte) (Comic, error) {
+	var comic Comic
+	if err := json.Unmarshal(body, &comic); err != nil {
+		return comic, err
+	}
+	if comic.Published == nil {
+		if published, err := parsePublished(comic); err == nil {
+			comic.Published = &published
+		}
+	}
+	return comic, nil
+}
+
+// parsePublished returns the publication date of a comic from its day, month and year
+func parsePublished(comic Comic) (Date, error) {
+	year, errYear := strconv.Atoi(comic.Year)
+	month, errMonth := strconv.Atoi(comic.Month)
+	day, errDay := strconv.Atoi(comic.Day)
+	if errYear != nil || errMonth != nil || errDay != nil {
+		return Date{}, errors.New("the publication date is incomplete")
+	}
+	date := NewDate(year, time.Month(month), day)
+	// time.Date normalizes the dates that are out of range, e.g. February 30th, which are not valid
+	if date.Year() != year || date.Month() != time.Month(month) || date.Day() != day {
+		return Date{}, errors.New("the publication date does not exist")
+	}
+	return date, nil
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type DateUnitSuite struct {
+	suite.Suite
+}
+
+func TestDateUnitSuite(t *testing.T) {
+	suite.Run(t, &DateUnitSuite{})
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *DateUnitSuite) TestParsePublished() {
+	testCases := []struct {
+		name           string
+		comic          Comic
+		expectedOutput Date
+		expectedError  error
+	}{
+		{"Valid Date", Comic{Day: "9", Month: "3", Year: "2014"}, NewDate(2014, time.March, 9), nil},
+		{"Leap Day", Comic{Day: "29", Month: "2", Year: "2012"}, NewDate(2012, time.February, 29), nil},
+		{"Missing Day", Comic{Month: "3", Year: "2014"}, Date{}, errors.New("the publication date is incomplete")},
+		{"Out Of Range", Comic{Day: "29", Month: "2", Year: "2014"}, Date{}, errors.New("the publication date does not exist")},
+		{"Invalid Month", Comic{Day: "1", Month: "13", Year: "2014"}, Date{}, errors.New("the publication date does not exist")},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			published, err := parsePublished(test.comic)
+			us.Equal(test.expectedError, err)
+			us.Equal(test.expectedOutput, published)
+		})
+	}
+}
+
+func (us *DateUnitSuite) TestParseComic() {
+	comic, err := parseComic([]byte(`{"num": 1, "day": "1", "month": "1", "year": "2006"}`))
+	us.Nil(err)
+	us.Require().NotNil(comic.Published)
+	us.Equal("2006-01-01", comic.Published.String())
+
+	// Comics with an invalid date are kept, without one
+	comic, err = parseComic([]byte(`{"num": 2, "day": "31", "month": "4", "year": "2006"}`))
+	us.Nil(err)
+	us.Equal(2, comic.Num)
+	us.Nil(comic.Published)
+
+	// Stored comics keep the date they were ingested with
+	comic, err = parseComic([]byte(`{"num": 3, "published": "2007-08-15"}`))
+	us.Nil(err)
+	us.Equal(NewDate(2007, time.August, 15), *comic.Published)
+
+	_, err = parseComic([]byte(`{"num": 4, "published": "15/08/2007"}`))
+	us.NotNil(err)
+}
+
+func (us *DateUnitSuite) TestMarshal() {
+	published := NewDate(2007, time.August, 15)
+	comic := Comic{Num: 303, Published: &published}
+
+	b, err := json.Marshal(comic)
+	us.Nil(err)
+	us.Contains(string(b), `"published":"2007-08-15"`)
+
+	b, err = xml.Marshal(comic)
+	us.Nil(err)
+	us.Contains(string(b), `<published>2007-08-15</published>`)
+
+	b, err = yaml.Marshal(comic)
+	us.Nil(err)
+	us.Contains(string(b), "published: \"2007-08-15\"\n")
+
+	// The date is left out when there is none
+	b, err = json.Marshal(Comic{Num: 303})
+	us.Nil(err)
+	us.NotContains(string(b), "published")
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// feedSize is the number of most recent comics that a feed covers when no range is given
+	feedSize = 20
+
+	mimeRSS  = "application/rss+xml"
+	mimeAtom = "application/atom+xml"
+
+	feedTitle = "xkcd comics"
+)
+
+// rssFeed is an RSS 2.0 document
+type rssFeed struct {
+	XMLName   xml.Name  `xml:"rss"`
+	Version   string    `xml:"version,attr"`
+	AtomNS    string    `xml:"xmlns:atom,attr"`
+	Title     string    `xml:"channel>title"`
+	Link      string    `xml:"channel>link"`
+	Desc      string    `xml:"channel>description"`
+	Self      atomLink  `xml:"channel>atom:link"`
+	BuildDate string    `xml:"channel>lastBuildDate"`
+	Items     []rssItem `xml:"channel>item"`
+}
+
+// rssItem is an item of an RSS 2.0 document
+type rssItem struct {
+	Title   string  `xml:"title"`
+	Link    string  `xml:"link"`
+	GUID    rssGUID `xml:"guid"`
+	Desc    string  `xml:"description"`
+	PubDate string  `xml:"pubDate,omitempty"`
+}
+
+// rssGUID is the unique identifier of an RSS item
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomFeed is an Atom 1.0 document
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  string      `xml:"author>name"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomEntry is an entry of an Atom 1.0 document
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Summary   string      `xml:"summary"`
+	Content   atomContent `xml:"content"`
+}
+
+// atomLink is a link of an Atom 1.0 document, also used by RSS 2.0 documents to point to themselves
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// atomContent is the content of an Atom entry
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// GetRSSFeed is a gin handler function that returns the comics selected like GetComics does as an
+// RSS 2.0 document, see queryFeed
+func (ctrl *Controller) GetRSSFeed(c *gin.Context) {
+	comics, ok := ctrl.queryFeed(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	feed := rssFeed{
+		Version:   "2.0",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		Title:     feedTitle,
+		Link:      ctrl.Cfg.UpstreamURL,
+		Desc:      "Comics from " + ctrl.Cfg.UpstreamURL,
+		Self:      atomLink{Href: ctrl.requestURL(c), Rel: "self", Type: mimeRSS},
+		BuildDate: now.Format(time.RFC1123Z),
+	}
+	for _, comic := range comics {
+		link := ctrl.comicURL(comic)
+		item := rssItem{
+			Title: comic.Title,
+			Link:  link,
+			GUID:  rssGUID{IsPermaLink: true, Value: link},
+			Desc:  comicHTML(comic),
+		}
+		if comic.Published != nil {
+			item.PubDate = comic.Published.Format(time.RFC1123Z)
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	setLastModified(c, comics...)
+	writeFeed(c, mimeRSS, feed)
+}
+
+// GetAtomFeed is a gin handler function that returns the comics selected like GetComics does as an
+// Atom 1.0 document, see queryFeed
+func (ctrl *Controller) GetAtomFeed(c *gin.Context) {
+	comics, ok := ctrl.queryFeed(c)
+	if !ok {
+		return
+	}
+
+	// The feed is as recent as its most recent comic, and entries must have a date, hence the ones
+	// without a valid one are dated from the time of the request
+	now := time.Now().UTC()
+	var updated time.Time
+	self := ctrl.requestURL(c)
+	feed := atomFeed{
+		Title: feedTitle,
+		ID:    self,
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: mimeAtom},
+			{Href: ctrl.Cfg.UpstreamURL, Rel: "alternate", Type: "text/html"},
+		},
+		Author: "xkcd",
+	}
+	for _, comic := range comics {
+		link := ctrl.comicURL(comic)
+		entry := atomEntry{
+			Title:   comic.Title,
+			ID:      link,
+			Link:    atomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Updated: now.Format(time.RFC3339),
+			Summary: comic.Alt,
+			Content: atomContent{Type: "html", Value: comicHTML(comic)},
+		}
+		if comic.Published != nil {
+			entry.Updated = comic.Published.Format(time.RFC3339)
+			entry.Published = entry.Updated
+			if comic.Published.After(updated) {
+				updated = comic.Published.Time
+			}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	if updated.IsZero() {
+		updated = now
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+	setLastModified(c, comics...)
+	writeFeed(c, mimeAtom, feed)
+}
+
+// queryFeed returns the comics of a feed, selected by the same query parameters as GetComics. As feed
+// readers poll a fixed URL, the range is optional and defaults to the most recent comics.
+func (ctrl *Controller) queryFeed(c *gin.Context) ([]Comic, bool) {
+	var (
+		start, end int
+		err        error
+	)
+	if !c.Request.URL.Query().Has("start") && !c.Request.URL.Query().Has("end") {
+		latest, err := ctrl.getLatestComic(c.Request.Context())
+		if err != nil {
+			abortWithFetchError(c, err)
+			return nil, false
+		}
+		start, end = latest.Num-feedSize+1, latest.Num
+		if start < 1 {
+			start = 1
+		}
+	} else if start, end, err = getStartEnd(c, ctrl.Cfg.MaxRangeSize); err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	response, ok := ctrl.queryComics(c, start, end)
+	return response.Comics, ok
+}
+
+// writeFeed responds with the XML document of a feed
+func writeFeed(c *gin.Context, contentType string, feed any) {
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		AbortWithError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, contentType+"; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// comicURL returns the URL of the page of the comic on upstream
+func (ctrl *Controller) comicURL(comic Comic) string {
+	return fmt.Sprintf("%s/%d/", strings.TrimSuffix(ctrl.Cfg.UpstreamURL, "/"), comic.Num)
+}
+
+// comicHTML returns the HTML representation of the comic, i.e. its image followed by its alt text
+func comicHTML(comic Comic) string {
+	return fmt.Sprintf(`<img src="%s" alt="%s" title="%s"/><p>%s</p>`,
+		html.EscapeString(comic.Img), html.EscapeString(comic.SafeTitle), html.EscapeString(comic.Alt), html.EscapeString(comic.Alt))
+}
+
+// requestURL returns the absolute URL of the request, as seen by the client
+func (ctrl *Controller) requestURL(c *gin.Context) string {
+	return ctrl.baseURL(c) + c.Request.URL.RequestURI()
+}
+
+// baseURL returns the configured public base URL of the API. When there is none, it falls back to the
+// host that the request was sent to, the forwarded headers never being trusted since the URLs built from
+// them could poison the shared caches.
+func (ctrl *Controller) baseURL(c *gin.Context) string {
+	if ctrl.Cfg.PublicBaseURL != "" {
+		return strings.TrimSuffix(ctrl.Cfg.PublicBaseURL, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
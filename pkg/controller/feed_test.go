@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type FeedUnitSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func TestFeedUnitSuite(t *testing.T) {
+	suite.Run(t, &FeedUnitSuite{})
+}
+
+func (us *FeedUnitSuite) SetupSuite() {
+	conf := config.NewConfig()
+	conf.UpstreamURL = "https://xkcd.com"
+	controller := NewController(conf, NewFSSource("testdata"), nil)
+
+	us.router = gin.New()
+	us.router.GET("/feeds/comics.rss", controller.GetRSSFeed)
+	us.router.GET("/feeds/comics.atom", controller.GetAtomFeed)
+}
+
+// get returns the recorded response to a GET request
+func (us *FeedUnitSuite) get(path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, path, nil)
+	us.Require().Nil(err)
+	request.Host = "api.example.com"
+	us.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *FeedUnitSuite) TestRSSFeed() {
+	recorder := us.get("/feeds/comics.rss?start=40&end=42&sort=-num")
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal("application/rss+xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+	us.Contains(recorder.Body.String(), `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">`)
+	us.Contains(recorder.Body.String(), `<atom:link href="http://api.example.com/feeds/comics.rss?start=40&amp;end=42&amp;sort=-num" rel="self" type="application/rss+xml"></atom:link>`)
+
+	var feed rssFeed
+	us.Nil(xml.Unmarshal(recorder.Body.Bytes(), &feed))
+	us.Equal("xkcd comics", feed.Title)
+	us.Len(feed.Items, 3)
+
+	item := feed.Items[0]
+	us.Equal("Geico", item.Title)
+	us.Equal("https://xkcd.com/42/", item.Link)
+	us.Equal(rssGUID{IsPermaLink: true, Value: "https://xkcd.com/42/"}, item.GUID)
+	us.Equal(`<img src="https://imgs.xkcd.com/comics/geico.jpg" alt="Geico" title="David did this"/><p>David did this</p>`, item.Desc)
+	us.Equal("Sun, 01 Jan 2006 00:00:00 +0000", item.PubDate)
+}
+
+func (us *FeedUnitSuite) TestAtomFeed() {
+	recorder := us.get("/feeds/comics.atom")
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal("application/atom+xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	var feed atomFeed
+	us.Nil(xml.Unmarshal(recorder.Body.Bytes(), &feed))
+	us.Equal("http://api.example.com/feeds/comics.atom", feed.ID)
+	us.Equal("2006-01-01T00:00:00Z", feed.Updated)
+	us.Equal("xkcd", feed.Author)
+
+	// Without a range, the feed covers the most recent comics
+	var titles []string
+	for _, entry := range feed.Entries {
+		titles = append(titles, entry.Title)
+	}
+	us.Equal([]string{"Geico", "Light", "Old Drawing"}, titles)
+
+	entry := feed.Entries[1]
+	us.Equal("https://xkcd.com/40/", entry.ID)
+	us.Equal(atomLink{Href: "https://xkcd.com/40/", Rel: "alternate", Type: "text/html"}, entry.Link)
+	us.Equal("2006-01-01T00:00:00Z", entry.Published)
+	us.Equal("Like a beacon", entry.Summary)
+	us.Equal("html", entry.Content.Type)
+}
+
+func (us *FeedUnitSuite) TestFeedErrors() {
+	recorder := us.get("/feeds/comics.rss?start=40")
+	us.Equal(http.StatusBadRequest, recorder.Code)
+	us.Equal(`{"error":"please include the starting and ending comic numbers"}`, recorder.Body.String())
+
+	recorder = us.get("/feeds/comics.atom?start=40&end=42&month_parity=prime")
+	us.Equal(http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errSkipComic is returned by a fetchFunc in order to leave a comic out of the results without
+// failing the whole range
+var errSkipComic = errors.New("skip comic")
+
+// fetchFunc retrieves the metadata of a single comic book
+type fetchFunc func(ctx context.Context, num int) (Comic, error)
+
+// fetchComics retrieves every comic whose number is in between start and end (inclusive) using a
+// pool of at most 'workers' goroutines. The comics are returned in ascending order of their number
+// regardless of the order in which they were fetched, leaving out the ones that were skipped. The first
+// error that is encountered, or the cancellation of the parent context, stops all the remaining fetches.
+func fetchComics(ctx context.Context, start, end, workers int, fetch fetchFunc) ([]Comic, error) {
+	if end < start {
+		return nil, nil
+	}
+
+	// The comics are emitted one at a time, hence the results need no further synchronization
+	results := make([]Comic, end-start+1)
+	fetched := make([]bool, end-start+1)
+	err := streamComics(ctx, start, end, workers, fetch, func(num int, comic Comic) error {
+		results[num-start] = comic
+		fetched[num-start] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comics := results[:0]
+	for i := range results {
+		if fetched[i] {
+			comics = append(comics, results[i])
+		}
+	}
+	return comics, nil
+}
+
+// streamComics retrieves every comic whose number is in between start and end (inclusive) using a
+// pool of at most 'workers' goroutines, and emits them in the order in which they were fetched. The
+// emit function is never called concurrently, and an error returned by it stops the fetching just like
+// the first error returned by a fetch, or the cancellation of the parent context.
+func streamComics(ctx context.Context, start, end, workers int, fetch fetchFunc, emit func(num int, comic Comic) error) error {
+	if end < start {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	count := end - start + 1
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > count {
+		workers = count
+	}
+
+	type result struct {
+		num   int
+		comic Comic
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	// fail records the first error and cancels the rest of the fetches
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	jobs := make(chan int)
+	results := make(chan result)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for num := range jobs {
+				comic, err := fetch(ctx, num)
+				if errors.Is(err, errSkipComic) {
+					continue
+				}
+				if err != nil {
+					fail(err)
+					continue
+				}
+				select {
+				case results <- result{num, comic}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	// Hand out the comic numbers until the range is exhausted or the fetching is cancelled
+	go func() {
+	feed:
+		for num := start; num <= end; num++ {
+			select {
+			case jobs <- num:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	// Keep draining the results after a failure so that no worker is left blocked
+	for r := range results {
+		if ctx.Err() != nil {
+			continue
+		}
+		if err := emit(r.num, r.comic); err != nil {
+			fail(err)
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type FetchUnitSuite struct {
+	suite.Suite
+}
+
+func TestFetchUnitSuite(t *testing.T) {
+	suite.Run(t, &FetchUnitSuite{})
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *FetchUnitSuite) TestFetchComicsOrder() {
+	testCases := []struct {
+		name    string
+		start   int
+		end     int
+		workers int
+	}{
+		{"Single Worker", 1, 10, 1},
+		{"Multiple Workers", 1, 50, 8},
+		{"More Workers Than Comics", 5, 7, 32},
+		{"Invalid Worker Count", 1, 5, 0},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			fetch := func(ctx context.Context, num int) (Comic, error) {
+				// Finish in a random order to make sure that the results are reassembled
+				time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+				return Comic{Num: num}, nil
+			}
+
+			comics, err := fetchComics(context.Background(), test.start, test.end, test.workers, fetch)
+			us.Nil(err)
+			us.Len(comics, test.end-test.start+1)
+			for j, comic := range comics {
+				us.Equal(test.start+j, comic.Num)
+			}
+		})
+	}
+}
+
+func (us *FetchUnitSuite) TestFetchComicsEmptyRange() {
+	comics, err := fetchComics(context.Background(), 10, 5, 4, func(ctx context.Context, num int) (Comic, error) {
+		us.Fail("no comic should be fetched")
+		return Comic{}, nil
+	})
+	us.Nil(err)
+	us.Nil(comics)
+}
+
+func (us *FetchUnitSuite) TestFetchComicsBoundedConcurrency() {
+	var running, peak int32
+	fetch := func(ctx context.Context, num int) (Comic, error) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return Comic{Num: num}, nil
+	}
+
+	_, err := fetchComics(context.Background(), 1, 40, 4, fetch)
+	us.Nil(err)
+	us.LessOrEqual(atomic.LoadInt32(&peak), int32(4))
+}
+
+func (us *FetchUnitSuite) TestFetchComicsFirstError() {
+	var calls int32
+	expectedErr := errors.New("upstream is down")
+	fetch := func(ctx context.Context, num int) (Comic, error) {
+		atomic.AddInt32(&calls, 1)
+		if num == 3 {
+			return Comic{}, expectedErr
+		}
+		select {
+		case <-ctx.Done():
+			return Comic{}, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+			return Comic{Num: num}, nil
+		}
+	}
+
+	comics, err := fetchComics(context.Background(), 1, 1000, 4, fetch)
+	us.ErrorIs(err, expectedErr)
+	us.Nil(comics)
+	// The remaining comics should not have been fetched after the failure
+	us.Less(atomic.LoadInt32(&calls), int32(1000))
+}
+
+func (us *FetchUnitSuite) TestFetchComicsCancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	fetch := func(ctx context.Context, num int) (Comic, error) {
+		if num == 2 {
+			cancel()
+		}
+		<-ctx.Done()
+		return Comic{}, ctx.Err()
+	}
+
+	comics, err := fetchComics(ctx, 1, 100, 2, fetch)
+	us.ErrorIs(err, context.Canceled)
+	us.Nil(comics)
+}
+
+func (us *FetchUnitSuite) TestStreamComicsEmitError() {
+	var calls int32
+	fetch := func(ctx context.Context, num int) (Comic, error) {
+		atomic.AddInt32(&calls, 1)
+		return Comic{Num: num}, nil
+	}
+
+	// The consumer going away stops the fetching, like a client disconnecting
+	var emitted int
+	expectedErr := errors.New("client is gone")
+	err := streamComics(context.Background(), 1, 1000, 4, fetch, func(num int, comic Comic) error {
+		emitted++
+		if emitted == 5 {
+			return expectedErr
+		}
+		return nil
+	})
+	us.ErrorIs(err, expectedErr)
+	us.Equal(5, emitted)
+	us.Less(atomic.LoadInt32(&calls), int32(1000))
+}
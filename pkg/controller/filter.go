@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// comicFilter returns whether a comic should be kept in the results
+type comicFilter func(Comic) bool
+
+// parseFilters builds the pipeline of filters described by the query parameters of the request. The
+// comics are filtered by the parity of their month, 'defaultParity' unless 'month_parity' is given, and
+// optionally by 'year', 'year_from', 'year_to', 'month', 'published_after', 'published_before',
+// 'title_contains', 'has_transcript' and 'alt_contains'. The bounds of the publication date are exclusive,
+// and leave out the comics without one.
+func parseFilters(c *gin.Context, defaultParity string) ([]comicFilter, error) {
+	var filters []comicFilter
+
+	parity, err := getMonthParity(c, defaultParity)
+	if err != nil {
+		return nil, err
+	}
+	if parity != monthParityAny {
+		filters = append(filters, func(comic Comic) bool {
+			return matchesMonthParity(comic, parity)
+		})
+	}
+
+	year, ok, err := getOptionalInt(c, "year")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		filters = append(filters, func(comic Comic) bool {
+			y, err := strconv.Atoi(comic.Year)
+			return err == nil && y == year
+		})
+	}
+
+	yearFrom, hasFrom, err := getOptionalInt(c, "year_from")
+	if err != nil {
+		return nil, err
+	}
+	yearTo, hasTo, err := getOptionalInt(c, "year_to")
+	if err != nil {
+		return nil, err
+	}
+	if hasFrom && hasTo && yearFrom > yearTo {
+		return nil, errors.New("please make sure that 'year_from' is not greater than 'year_to'")
+	}
+	if hasFrom || hasTo {
+		filters = append(filters, func(comic Comic) bool {
+			y, err := strconv.Atoi(comic.Year)
+			return err == nil && (!hasFrom || y >= yearFrom) && (!hasTo || y <= yearTo)
+		})
+	}
+
+	month, ok, err := getOptionalInt(c, "month")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if month < 1 || month > 12 {
+			return nil, errors.New("please make sure that 'month' is in between 1 and 12")
+		}
+		filters = append(filters, func(comic Comic) bool {
+			m, err := strconv.Atoi(comic.Month)
+			return err == nil && m == month
+		})
+	}
+
+	after, hasAfter, err := getOptionalDate(c, "published_after")
+	if err != nil {
+		return nil, err
+	}
+	before, hasBefore, err := getOptionalDate(c, "published_before")
+	if err != nil {
+		return nil, err
+	}
+	if hasAfter && hasBefore && !after.Before(before.Time) {
+		return nil, errors.New("please make sure that 'published_after' is before 'published_before'")
+	}
+	if hasAfter || hasBefore {
+		filters = append(filters, func(comic Comic) bool {
+			return comic.Published != nil &&
+				(!hasAfter || comic.Published.After(after.Time)) &&
+				(!hasBefore || comic.Published.Before(before.Time))
+		})
+	}
+
+	if title := c.Query("title_contains"); title != "" {
+		filters = append(filters, func(comic Comic) bool {
+			return containsFold(comic.Title, title)
+		})
+	}
+
+	wantTranscript, ok, err := getOptionalBool(c, "has_transcript")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		filters = append(filters, func(comic Comic) bool {
+			return hasTranscript(comic) == wantTranscript
+		})
+	}
+
+	if alt := c.Query("alt_contains"); alt != "" {
+		filters = append(filters, func(comic Comic) bool {
+			return containsFold(comic.Alt, alt)
+		})
+	}
+
+	return filters, nil
+}
+
+// matchesFilters returns whether the comic is kept by every filter of the pipeline
+func matchesFilters(comic Comic, filters []comicFilter) bool {
+	for _, filter := range filters {
+		if !filter(comic) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTranscript returns whether the comic has a transcript, a blank one being no transcript at all
+func hasTranscript(comic Comic) bool {
+	return strings.TrimSpace(comic.Transcript) != ""
+}
+
+// containsFold returns whether substr is within s, ignoring the case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type FilterUnitSuite struct {
+	suite.Suite
+	comics []Comic
+}
+
+func TestFilterUnitSuite(t *testing.T) {
+	suite.Run(t, &FilterUnitSuite{})
+}
+
+func (us *FilterUnitSuite) SetupSuite() {
+	us.comics = []Comic{
+		{Num: 1, Title: "Barrel - Part 1", Month: "1", Year: "2006", Alt: "Don't we all."},
+		{Num: 303, Title: "Compiling", Month: "8", Year: "2007", Alt: "'Are you stealing those LCDs?'", Transcript: "[[Two stick figures are sword fighting]]"},
+		{Num: 927, Title: "Standards", Month: "7", Year: "2011", Alt: "Fortunately, the charging one has been solved now that we've all standardized on mini-USB."},
+		{Num: 1205, Title: "Is It Worth the Time?", Month: "4", Year: "2013", Alt: "Don't forget the time you spend finding the chart to look up what you save."},
+		{Num: 2347, Title: "Dependency", Month: "8", Year: "2020", Alt: "Someday ImageMagick will finally break for good."},
+	}
+}
+
+// newFilterContext returns a gin context for a request with the given query
+func newFilterContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/comics"+query, nil)
+	return c
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *FilterUnitSuite) TestParseFilters() {
+	testCases := []struct {
+		name           string
+		query          string
+		expectedOutput []int
+	}{
+		{"Odd Months By Default", "", []int{1, 927}},
+		{"Even Months", "?month_parity=even", []int{303, 1205, 2347}},
+		{"Any Month", "?month_parity=any", []int{1, 303, 927, 1205, 2347}},
+		{"Year", "?month_parity=any&year=2007", []int{303}},
+		{"Year Range", "?month_parity=any&year_from=2007&year_to=2013", []int{303, 927, 1205}},
+		{"Open Year Range", "?month_parity=any&year_from=2011", []int{927, 1205, 2347}},
+		{"Month", "?month_parity=any&month=8", []int{303, 2347}},
+		{"Title Contains", "?month_parity=any&title_contains=IT", []int{1205}},
+		{"Has Transcript", "?month_parity=any&has_transcript=true", []int{303}},
+		{"Has No Transcript", "?has_transcript=false", []int{1, 927}},
+		{"Alt Contains", "?month_parity=any&alt_contains=don't", []int{1, 1205}},
+		{"Composed Filters", "?month_parity=even&year_to=2015&alt_contains=you", []int{303, 1205}},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			filters, err := parseFilters(newFilterContext(test.query), monthParityOdd)
+			us.Nil(err)
+
+			var nums []int
+			for _, comic := range us.comics {
+				if matchesFilters(comic, filters) {
+					nums = append(nums, comic.Num)
+				}
+			}
+			us.Equal(test.expectedOutput, nums)
+		})
+	}
+}
+
+func (us *FilterUnitSuite) TestParseFiltersValidation() {
+	testCases := []struct {
+		name          string
+		query         string
+		expectedError error
+	}{
+		{"Invalid Parity", "?month_parity=prime", errors.New("please make sure that 'month_parity' is one of 'odd', 'even' or 'any'")},
+		{"Invalid Year", "?year=MMVI", errors.New("please make sure that 'year' is an integer")},
+		{"Invalid Year Range", "?year_from=2020&year_to=2006", errors.New("please make sure that 'year_from' is not greater than 'year_to'")},
+		{"Month Out Of Range", "?month=13", errors.New("please make sure that 'month' is in between 1 and 12")},
+		{"Invalid Transcript Flag", "?has_transcript=sometimes", errors.New("please make sure that 'has_transcript' is either true or false")},
+		{"Invalid Date", "?published_after=01/01/2006", errors.New("please make sure that 'published_after' is a date formatted as YYYY-MM-DD")},
+		{"Invalid Date Range", "?published_after=2011-01-01&published_before=2011-01-01", errors.New("please make sure that 'published_after' is before 'published_before'")},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			_, err := parseFilters(newFilterContext(test.query), monthParityOdd)
+			us.Equal(test.expectedError, err)
+		})
+	}
+}
+
+func (us *FilterUnitSuite) TestPublishedFilters() {
+	dates := []Date{NewDate(2006, time.January, 1), NewDate(2007, time.August, 15), NewDate(2011, time.July, 20)}
+	comics := []Comic{
+		{Num: 1, Month: "1", Published: &dates[0]},
+		{Num: 303, Month: "8", Published: &dates[1]},
+		{Num: 927, Month: "7", Published: &dates[2]},
+		{Num: 1000, Month: "1"},
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedOutput []int
+	}{
+		{"Published After", "?month_parity=any&published_after=2006-01-01", []int{303, 927}},
+		{"Published Before", "?month_parity=any&published_before=2011-07-20", []int{1, 303}},
+		{"Published Between", "?month_parity=any&published_after=2006-01-01&published_before=2011-07-20", []int{303}},
+		{"Without Bounds", "?month_parity=any", []int{1, 303, 927, 1000}},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			filters, err := parseFilters(newFilterContext(test.query), monthParityOdd)
+			us.Nil(err)
+
+			var nums []int
+			for _, comic := range comics {
+				if matchesFilters(comic, filters) {
+					nums = append(nums, comic.Num)
+				}
+			}
+			us.Equal(test.expectedOutput, nums)
+		})
+	}
+}
@@ -1,37 +1,79 @@
 package controller
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AbortWithError returns a JSON body containing the error message back to the sender prior to
-// creating a *gin.Error object to be logged
+// AbortWithError returns a body containing the error message back to the sender, in the format of
+// the response, prior to creating a *gin.Error object to be logged
 func AbortWithError(c *gin.Context, status int, err error) *gin.Error {
 	error := c.Error(err)
-	c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+	c.Abort()
+	renderResponse(c, status, gin.H{"error": err.Error()})
 	return error
 }
 
-// isAlphabetical returns true if the inputted character belongs in the alphabet
-func isAlphabetical(char byte) bool {
-	return ('a' <= char && char <= 'z') || ('A' <= char && char <= 'Z')
+const (
+	monthParityOdd  = "odd"
+	monthParityEven = "even"
+	monthParityAny  = "any"
+)
+
+// abortWithFetchError responds with the status code corresponding to an error that occurred while
+// fetching comics. Errors returned by upstream are described along with their status code, and upstream
+// being unreachable is a gateway failure as well.
+func abortWithFetchError(c *gin.Context, err error) *gin.Error {
+	var (
+		upstreamErr  *UpstreamError
+		transportErr *url.Error
+	)
+	switch {
+	case errors.Is(err, context.Canceled) && c.Request.Context().Err() != nil:
+		return AbortWithError(c, statusClientClosedRequest, err)
+	case errors.Is(err, ErrComicNotFound):
+		return AbortWithError(c, http.StatusNotFound, err)
+	case errors.Is(err, ErrCircuitOpen):
+		return AbortWithError(c, http.StatusServiceUnavailable, err)
+	case errors.As(err, &upstreamErr):
+		error := c.Error(err)
+		c.Abort()
+		renderResponse(c, http.StatusBadGateway, gin.H{
+			"error":           err.Error(),
+			"upstream_status": upstreamErr.StatusCode,
+		})
+		return error
+	case errors.As(err, &transportErr):
+		return AbortWithError(c, http.StatusBadGateway, err)
+	}
+	return AbortWithError(c, http.StatusInternalServerError, err)
+}
+
+// isAlphabetical returns true if the inputted character is a letter of any alphabet
+func isAlphabetical(char rune) bool {
+	return unicode.IsLetter(char)
 }
 
-// findFirstAlphabeticalCharacter returns the first alphabetical character of the inputted string
+// findFirstAlphabeticalCharacter returns the inputted string starting from its first alphabetical
+// character, or the empty string if it has none
 func findFirstAlphabeticalCharacter(str string) string {
-	for i := 1; i < len(str); i++ {
-		if isAlphabetical(str[i]) {
-			return str[i:]
-		}
+	if i := strings.IndexFunc(str, isAlphabetical); i >= 0 {
+		return str[i:]
 	}
-	return str
+	return ""
 }
 
-// getStartEnd returns the start & end query parameters from the request and parses them into integers
-func getStartEnd(c *gin.Context) (int, int, error) {
+// getStartEnd returns the start & end query parameters from the request and parses them into integers.
+// Ranges holding more than 'maxRange' comics are rejected, unless it is 0.
+func getStartEnd(c *gin.Context, maxRange int) (int, int, error) {
 	queryParams := c.Request.URL.Query()
 
 	if !queryParams.Has("start") || !queryParams.Has("end") {
@@ -46,6 +88,97 @@ func getStartEnd(c *gin.Context) (int, int, error) {
 	if err != nil {
 		return 0, 0, errors.New("please make sure that the ending number is an integer")
 	}
+	// The size of the range wraps around when the numbers are far enough apart
+	if size := end - start + 1; maxRange > 0 && end >= start && (size <= 0 || size > maxRange) {
+		return 0, 0, fmt.Errorf("please make sure that the range holds at most %d comics", maxRange)
+	}
 
 	return start, end, nil
 }
+
+// getStrict returns the optional 'strict' query parameter from the request, parsed into a boolean
+func getStrict(c *gin.Context) (bool, error) {
+	strict, _, err := getOptionalBool(c, "strict")
+	return strict, err
+}
+
+// getOptionalBool returns the given query parameter from the request parsed into a boolean, and
+// whether it was present at all
+func getOptionalBool(c *gin.Context, key string) (bool, bool, error) {
+	value := c.Query(key)
+	if value == "" {
+		return false, false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false, fmt.Errorf("please make sure that '%s' is either true or false", key)
+	}
+	return parsed, true, nil
+}
+
+// getOptionalInt returns the given query parameter from the request parsed into an integer, and
+// whether it was present at all
+func getOptionalInt(c *gin.Context, key string) (int, bool, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, false, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("please make sure that '%s' is an integer", key)
+	}
+	return parsed, true, nil
+}
+
+// getOptionalDate returns the given query parameter from the request parsed into a Date, and whether
+// it was present at all
+func getOptionalDate(c *gin.Context, key string) (Date, bool, error) {
+	value := c.Query(key)
+	if value == "" {
+		return Date{}, false, nil
+	}
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return Date{}, false, fmt.Errorf("please make sure that '%s' is a date formatted as YYYY-MM-DD", key)
+	}
+	return parsed, true, nil
+}
+
+// getMonthParity returns the optional 'month_parity' query parameter from the request, which is
+// either 'odd', 'even' or 'any', and defaults to the given value
+func getMonthParity(c *gin.Context, defaultValue string) (string, error) {
+	switch parity := c.DefaultQuery("month_parity", defaultValue); parity {
+	case monthParityOdd, monthParityEven, monthParityAny:
+		return parity, nil
+	}
+	return "", errors.New("please make sure that 'month_parity' is one of 'odd', 'even' or 'any'")
+}
+
+// matchesMonthParity returns whether the comic was published on a month of the given parity
+func matchesMonthParity(comic Comic, parity string) bool {
+	if parity == monthParityAny {
+		return true
+	}
+	// A comic without a valid month has no parity
+	m, err := strconv.Atoi(comic.Month)
+	if err != nil {
+		return false
+	}
+	switch parity {
+	case monthParityOdd:
+		return m%2 != 0
+	case monthParityEven:
+		return m%2 == 0
+	}
+	return true
+}
+
+// contains returns whether the slice contains the given value
+func contains[T comparable](slice []T, value T) bool {
+	for _, v := range slice {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
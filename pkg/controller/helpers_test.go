@@ -25,7 +25,7 @@ func TestHelpersUnitSuite(t *testing.T) {
 func (us *HelpersUnitSuite) TestIsAlphabetical() {
 	testCases := []struct {
 		name           string
-		input          byte
+		input          rune
 		expectedOutput bool
 	}{
 		{
@@ -43,6 +43,16 @@ func (us *HelpersUnitSuite) TestIsAlphabetical() {
 			'@',
 			false,
 		},
+		{
+			"Accented Character",
+			'É',
+			true,
+		},
+		{
+			"Digit",
+			'7',
+			false,
+		},
 	}
 
 	for i := range testCases {
@@ -79,6 +89,21 @@ func (us *HelpersUnitSuite) TestFindFirstAlphabeticalCharacter() {
 			"     spaces",
 			"spaces",
 		},
+		{
+			"Accented First Character",
+			"Électricité",
+			"Électricité",
+		},
+		{
+			"No Alphabetical Character",
+			"1337 ?!",
+			"",
+		},
+		{
+			"Empty String",
+			"",
+			"",
+		},
 	}
 
 	for i := range testCases {
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/httputil"
+)
+
+const (
+	// maxImagePixels is the maximum number of pixels of the images that are decoded into thumbnails, so
+	// that small but highly compressed images cannot exhaust the memory
+	maxImagePixels = 25_000_000
+	// thumbnailQuality is the quality of the JPEG thumbnails
+	thumbnailQuality = 85
+)
+
+// thumbnailWidths are the widths in pixels of the thumbnails that can be generated, which are few so
+// that the thumbnails are generated and cached only once
+var thumbnailWidths = []int{100, 200, 400, 800}
+
+var (
+	// errImagesUnsupported is returned when the comic source is not able to serve images
+	errImagesUnsupported = errors.New("the comic source does not serve images")
+	// errImageTooLarge is returned when an image has too many pixels to be thumbnailed
+	errImageTooLarge = errors.New("the image is too large to be thumbnailed")
+)
+
+// GetComicImage is a gin handler function that proxies the image of the comic whose number is given in
+// the path, so that clients do not need to reach the host of the images. The images are cached on disk,
+// and thumbnails are generated when the 'width' query parameter is smaller than the width of the image.
+// Clients may cache the images, and revalidate them with their ETag.
+func (ctrl *Controller) GetComicImage(c *gin.Context) {
+	num, err := strconv.Atoi(c.Param("num"))
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, errors.New("please make sure that the comic number is an integer"))
+		return
+	}
+	width, hasWidth, err := getOptionalInt(c, "width")
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+	if hasWidth && !contains(thumbnailWidths, width) {
+		AbortWithError(c, http.StatusBadRequest, errors.New("please make sure that 'width' is one of 100, 200, 400 or 800"))
+		return
+	}
+
+	img, err := ctrl.getImage(c, num, width)
+	switch {
+	case errors.Is(err, ErrImageNotFound):
+		AbortWithError(c, http.StatusNotFound, err)
+		return
+	case errors.Is(err, errImagesUnsupported):
+		AbortWithError(c, http.StatusNotImplemented, err)
+		return
+	case errors.Is(err, errImageTooLarge):
+		AbortWithError(c, http.StatusUnprocessableEntity, err)
+		return
+	case err != nil:
+		abortWithFetchError(c, err)
+		return
+	}
+
+	// Images never change, hence their content makes a strong validator
+	sum := sha256.Sum256(img)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", ctrl.Cfg.ImageMaxAge))
+	if httputil.MatchesETag(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, http.DetectContentType(img), img)
+}
+
+// getImage returns the image of the comic with the given number from the image cache if possible, and
+// from the source otherwise. Thumbnails of the given width, if any, are generated from the full image.
+func (ctrl *Controller) getImage(c *gin.Context, num, width int) ([]byte, error) {
+	key := strconv.Itoa(num)
+	if width > 0 {
+		key += "-" + strconv.Itoa(width) + "w"
+	}
+	if img, ok := ctrl.images.Get(key); ok {
+		return img, nil
+	}
+
+	var (
+		img []byte
+		err error
+	)
+	if width > 0 {
+		if img, err = ctrl.getImage(c, num, 0); err != nil {
+			return nil, err
+		}
+		if img, err = thumbnail(img, width); err != nil {
+			return nil, err
+		}
+	} else {
+		source, ok := ctrl.Source.(ImageSource)
+		if !ok {
+			return nil, errImagesUnsupported
+		}
+		comic, err := ctrl.getComic(c.Request.Context(), num)
+		if err != nil {
+			return nil, err
+		}
+		if img, err = source.GetImage(c.Request.Context(), comic); err != nil {
+			return nil, err
+		}
+	}
+
+	// The image is served regardless of whether it could be cached
+	if err := ctrl.images.Add(key, img); err != nil {
+		_ = c.Error(err)
+	}
+	return img, nil
+}
+
+// thumbnail scales the image down to the given width, keeping its aspect ratio. JPEG images stay JPEG,
+// and the others are encoded as PNG. Images that are not wider than the given width are left untouched,
+// and images having more than maxImagePixels pixels are rejected before they are decoded.
+func thumbnail(img []byte, width int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(img))
+	if err != nil {
+		return nil, fmt.Errorf("the image could not be decoded: %w", err)
+	}
+	if int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return nil, errImageTooLarge
+	}
+	if config.Width <= width {
+		return img, nil
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, fmt.Errorf("the image could not be decoded: %w", err)
+	}
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		return img, nil
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := resize(src, width, height)
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailQuality})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	return buf.Bytes(), err
+}
+
+// resize scales the image down to the given size with a box filter, i.e. every pixel of the result is
+// the average of the pixels of the image that it covers
+func resize(src image.Image, width, height int) *image.RGBA {
+	// Work on premultiplied RGBA pixels, which can be averaged as they are
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, (y+1)*srcHeight/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, (x+1)*srcWidth/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for i := range sum {
+						sum[i] += int(row[sx*4+i])
+					}
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8((sum[i] + count/2) / count)
+			}
+		}
+	}
+	return dst
+}
+
+// proxyImage returns the comic with its image URL pointing to the image proxy if the image URLs are
+// rewritten, and as it is otherwise
+func (ctrl *Controller) proxyImage(c *gin.Context, comic Comic) Comic {
+	if ctrl.Cfg.RewriteImageURLs && comic.Img != "" {
+		comic.Img = fmt.Sprintf("%s/comics/%d/image", ctrl.baseURL(c), comic.Num)
+	}
+	return comic
+}
+
+// proxyImages rewrites the image URLs of the comics in place, see proxyImage
+func (ctrl *Controller) proxyImages(c *gin.Context, comics []Comic) {
+	for i := range comics {
+		comics[i] = ctrl.proxyImage(c, comics[i])
+	}
+}
@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type ImageUnitSuite struct {
+	suite.Suite
+}
+
+func TestImageUnitSuite(t *testing.T) {
+	suite.Run(t, &ImageUnitSuite{})
+}
+
+// get performs a GET request on the router with the given headers
+func (us *ImageUnitSuite) get(router *gin.Engine, path string, header http.Header) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, path, nil)
+	us.Require().Nil(err)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *ImageUnitSuite) TestResize() {
+	// The left half of the image is black and the right half is white
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{0, 0, 0, 255}
+			if x >= 2 {
+				c = color.RGBA{255, 255, 255, 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	dst := resize(src, 2, 1)
+	us.Equal(image.Rect(0, 0, 2, 1), dst.Bounds())
+	us.Equal(color.RGBA{0, 0, 0, 255}, dst.At(0, 0))
+	us.Equal(color.RGBA{255, 255, 255, 255}, dst.At(1, 0))
+
+	dst = resize(src, 1, 1)
+	us.Equal(color.RGBA{128, 128, 128, 255}, dst.At(0, 0))
+}
+
+func (us *ImageUnitSuite) TestThumbnail() {
+	original, err := os.ReadFile(filepath.Join("testdata", "42", "geico.jpg"))
+	us.Require().Nil(err)
+
+	thumb, err := thumbnail(original, 50)
+	us.Nil(err)
+	decoded, format, err := image.DecodeConfig(bytes.NewReader(thumb))
+	us.Nil(err)
+	us.Equal("jpeg", format)
+	us.Equal(50, decoded.Width)
+	us.Equal(25, decoded.Height)
+
+	// Images are never scaled up
+	thumb, err = thumbnail(original, 400)
+	us.Nil(err)
+	us.Equal(original, thumb)
+
+	_, err = thumbnail([]byte("not an image"), 50)
+	us.NotNil(err)
+
+	// Images having too many pixels are rejected before they are decoded
+	_, err = thumbnail(pngHeader(100000, 100000), 50)
+	us.ErrorIs(err, errImageTooLarge)
+}
+
+// pngHeader returns the beginning of a PNG image of the given size, which is enough to decode its size
+func pngHeader(width, height uint32) []byte {
+	var ihdr bytes.Buffer
+	ihdr.WriteString("IHDR")
+	_ = binary.Write(&ihdr, binary.BigEndian, []uint32{width, height})
+	// 8 bits per sample, RGBA, default compression, filtering and no interlacing
+	ihdr.Write([]byte{8, 6, 0, 0, 0})
+
+	var img bytes.Buffer
+	img.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&img, binary.BigEndian, uint32(ihdr.Len()-4))
+	img.Write(ihdr.Bytes())
+	_ = binary.Write(&img, binary.BigEndian, crc32.ChecksumIEEE(ihdr.Bytes()))
+	return img.Bytes()
+}
+
+func (us *ImageUnitSuite) TestImageCache() {
+	dir := us.T().TempDir()
+	// The other files of the directory, such as the comic store, are never indexed nor evicted
+	us.Require().Nil(os.WriteFile(filepath.Join(dir, storeFileName), []byte("store"), 0o600))
+	cache := newImageCache(dir, 10)
+	us.Nil(cache.Load())
+	us.Equal(int64(0), cache.Size())
+
+	us.Nil(cache.Add("1", []byte("aaaa")))
+	us.Nil(cache.Add("2", []byte("bbbb")))
+	image, ok := cache.Get("1")
+	us.True(ok)
+	us.Equal("aaaa", string(image))
+
+	// The least recently used images are evicted until the cache fits in its size
+	us.Nil(cache.Add("3-100w", []byte("cccc")))
+	us.Equal(int64(8), cache.Size())
+	_, ok = cache.Get("2")
+	us.False(ok)
+	_, err := os.Stat(filepath.Join(dir, "2"))
+	us.True(os.IsNotExist(err))
+
+	// Images larger than the cache are not cached
+	us.Nil(cache.Add("4", []byte("ddddddddddd")))
+	_, ok = cache.Get("4")
+	us.False(ok)
+
+	// Only the keys of the images may be used
+	us.NotNil(cache.Add(storeFileName, []byte("eeee")))
+
+	// The images are found again after a restart
+	restarted := newImageCache(dir, 10)
+	us.Nil(restarted.Load())
+	us.Equal(int64(8), restarted.Size())
+	image, ok = restarted.Get("3-100w")
+	us.True(ok)
+	us.Equal("cccc", string(image))
+
+	// Evicting every image leaves the other files alone
+	us.Nil(newImageCache(dir, 1).Load())
+	store, err := os.ReadFile(filepath.Join(dir, storeFileName))
+	us.Nil(err)
+	us.Equal("store", string(store))
+
+	// A cache without a directory is disabled
+	var disabled *imageCache
+	us.Nil(newImageCache("", 10))
+	us.Nil(disabled.Load())
+	us.Nil(disabled.Add("1", []byte("aaaa")))
+	_, ok = disabled.Get("1")
+	us.False(ok)
+}
+
+func (us *ImageUnitSuite) TestImageEndpoint() {
+	original, err := os.ReadFile(filepath.Join("testdata", "42", "geico.jpg"))
+	us.Require().Nil(err)
+
+	conf := config.NewConfig()
+	conf.ImageCacheDir = us.T().TempDir()
+	conf.ImageCacheSize = 1
+	conf.ImageMaxAge = 60
+	controller := NewController(conf, NewFSSource("testdata"), nil)
+	us.Require().Nil(controller.LoadImageCache())
+
+	router := gin.New()
+	router.GET("/comics/:num/image", controller.GetComicImage)
+
+	recorder := us.get(router, "/comics/42/image", nil)
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal("image/jpeg", recorder.Header().Get("Content-Type"))
+	us.Equal("public, max-age=60", recorder.Header().Get("Cache-Control"))
+	us.Equal(original, recorder.Body.Bytes())
+	etag := recorder.Header().Get("ETag")
+	us.NotEmpty(etag)
+
+	// The image is revalidated with its entity tag
+	recorder = us.get(router, "/comics/42/image", http.Header{"If-None-Match": {`"other", ` + etag}})
+	us.Equal(http.StatusNotModified, recorder.Code)
+	us.Empty(recorder.Body.Bytes())
+
+	// Thumbnails are generated from the full image, and cached along with it
+	recorder = us.get(router, "/comics/42/image?width=100", nil)
+	us.Equal(http.StatusOK, recorder.Code)
+	decoded, _, err := image.DecodeConfig(bytes.NewReader(recorder.Body.Bytes()))
+	us.Nil(err)
+	us.Equal(100, decoded.Width)
+	us.NotEqual(etag, recorder.Header().Get("ETag"))
+	for _, key := range []string{"42", "42-100w"} {
+		_, err := os.Stat(filepath.Join(conf.ImageCacheDir, key))
+		us.Nil(err)
+	}
+
+	testCases := []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{"Missing Image", "/comics/40/image", 404, `{"error":"image not found"}`},
+		{"Missing Comic", "/comics/404/image", 404, `{"error":"testdata/404/info.0.json: comic not found"}`},
+		{"Invalid Width", "/comics/42/image?width=0", 400, `{"error":"please make sure that 'width' is one of 100, 200, 400 or 800"}`},
+		{"Arbitrary Width", "/comics/42/image?width=150", 400, `{"error":"please make sure that 'width' is one of 100, 200, 400 or 800"}`},
+		{"Invalid Number", "/comics/forty-two/image", 400, `{"error":"please make sure that the comic number is an integer"}`},
+	}
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := us.get(router, test.path, nil)
+			us.Equal(test.expectedStatus, recorder.Code)
+			us.Equal(test.expectedResponse, recorder.Body.String())
+		})
+	}
+
+	// Sources that do not serve images cannot be proxied
+	unsupported := NewController(conf, &failingSource{}, nil)
+	router = gin.New()
+	router.GET("/comics/:num/image", unsupported.GetComicImage)
+	recorder = us.get(router, "/comics/42/image", nil)
+	us.Equal(http.StatusNotImplemented, recorder.Code)
+}
+
+func (us *ImageUnitSuite) TestRewriteImageURLs() {
+	testCases := []struct {
+		name          string
+		rewrite       bool
+		publicBaseURL string
+		expectedImg   string
+	}{
+		{"Not Rewritten", false, "", "https://imgs.xkcd.com/comics/geico.jpg"},
+		{"Request Host", true, "", "http://api.example.com/comics/42/image"},
+		{"Public Base URL", true, "https://cdn.example.com/", "https://cdn.example.com/comics/42/image"},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			conf := config.NewConfig()
+			conf.RewriteImageURLs = test.rewrite
+			conf.PublicBaseURL = test.publicBaseURL
+			controller := NewController(conf, NewFSSource("testdata"), nil)
+
+			router := gin.New()
+			router.GET("/comics/:num", controller.GetComic)
+			// The forwarded headers are never trusted
+			recorder := us.get(router, "http://api.example.com/comics/42", http.Header{"X-Forwarded-Proto": {"gopher"}})
+			us.Equal(http.StatusOK, recorder.Code)
+			us.Contains(recorder.Body.String(), `"img":"`+test.expectedImg+`"`)
+		})
+	}
+}
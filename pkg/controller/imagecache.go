@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// imageCache is a bounded, on-disk LRU cache of images whose total size is limited. The order in which
+// the images were used is kept in memory, and restored from their modification time on Load.
+// A nil *imageCache is a valid, always empty cache.
+type imageCache struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	size    int64
+	entries map[string]*list.Element
+	// order holds the entries from the most to the least recently used
+	order *list.List
+}
+
+// imageCacheEntry is a single image of the imageCache
+type imageCacheEntry struct {
+	key  string
+	size int64
+}
+
+// newImageCache returns a cache storing at most 'maxSize' bytes of images in the given directory, or nil
+// if there is no directory or the size is not positive
+func newImageCache(dir string, maxSize int64) *imageCache {
+	if dir == "" || maxSize <= 0 {
+		return nil
+	}
+	return &imageCache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// imageKeyPattern matches the keys of the images, i.e. the number of the comic followed by the width of
+// the thumbnail if it is one, so that the other files of the directory are never touched
+var imageKeyPattern = regexp.MustCompile(`^[0-9]+(-[0-9]+w)?$`)
+
+// Load creates the directory of the cache, and indexes the images that it already contains from the
+// most to the least recently modified one, evicting the ones that do not fit
+func (ic *imageCache) Load() error {
+	if ic == nil {
+		return nil
+	}
+	if err := os.MkdirAll(ic.dir, 0o755); err != nil {
+		return err
+	}
+	dirEntries, err := os.ReadDir(ic.dir)
+	if err != nil {
+		return err
+	}
+	type file struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	for _, dirEntry := range dirEntries {
+		// Temporary files, which are left over from interrupted writes, do not match the keys either
+		if !imageKeyPattern.MatchString(dirEntry.Name()) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, file{dirEntry.Name(), info.Size(), info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+
+	ic.mu.Lock()
+	for _, f := range files {
+		if _, ok := ic.entries[f.key]; !ok {
+			ic.entries[f.key] = ic.order.PushBack(&imageCacheEntry{key: f.key, size: f.size})
+			ic.size += f.size
+		}
+	}
+	evicted := ic.evict()
+	ic.mu.Unlock()
+	return ic.removeFiles(evicted)
+}
+
+// Get returns the cached image with the given key, if it is present. The lock is only held to look the
+// image up, not while it is read.
+func (ic *imageCache) Get(key string) ([]byte, bool) {
+	if ic == nil {
+		return nil, false
+	}
+	ic.mu.Lock()
+	elem, ok := ic.entries[key]
+	if ok {
+		ic.order.MoveToFront(elem)
+	}
+	ic.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := filepath.Join(ic.dir, key)
+	image, err := os.ReadFile(path)
+	if err != nil {
+		// The file was removed behind our back
+		ic.mu.Lock()
+		if ic.entries[key] == elem {
+			ic.remove(elem)
+		}
+		ic.mu.Unlock()
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return image, true
+}
+
+// Add writes the image into the cache, evicting the least recently used ones until the cache fits in
+// its maximum size. Images larger than the cache are not cached. The lock is only held to account for
+// the image once it was written.
+func (ic *imageCache) Add(key string, image []byte) error {
+	if ic == nil || int64(len(image)) > ic.maxSize {
+		return nil
+	}
+	if !imageKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid image cache key '%s'", key)
+	}
+
+	// The image is written to a temporary file first, so that a partially written image is never read
+	tmp, err := os.CreateTemp(ic.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(image)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(ic.dir, key))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	ic.mu.Lock()
+	if elem, ok := ic.entries[key]; ok {
+		entry := elem.Value.(*imageCacheEntry)
+		ic.size += int64(len(image)) - entry.size
+		entry.size = int64(len(image))
+		ic.order.MoveToFront(elem)
+	} else {
+		ic.entries[key] = ic.order.PushFront(&imageCacheEntry{key: key, size: int64(len(image))})
+		ic.size += int64(len(image))
+	}
+	evicted := ic.evict()
+	ic.mu.Unlock()
+	return ic.removeFiles(evicted)
+}
+
+// Size returns the total size in bytes of the cached images
+func (ic *imageCache) Size() int64 {
+	if ic == nil {
+		return 0
+	}
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	return ic.size
+}
+
+// evict forgets the least recently used images until the cache fits in its maximum size, and returns
+// their keys so that their files can be removed once the lock is released. The caller must hold the lock.
+func (ic *imageCache) evict() []string {
+	var evicted []string
+	for ic.size > ic.maxSize {
+		elem := ic.order.Back()
+		evicted = append(evicted, elem.Value.(*imageCacheEntry).key)
+		ic.remove(elem)
+	}
+	return evicted
+}
+
+// removeFiles removes the files of the evicted images
+func (ic *imageCache) removeFiles(keys []string) error {
+	for _, key := range keys {
+		err := os.Remove(filepath.Join(ic.dir, key))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove forgets the given element, the caller must hold the lock
+func (ic *imageCache) remove(elem *list.Element) {
+	entry := elem.Value.(*imageCacheEntry)
+	ic.order.Remove(elem)
+	delete(ic.entries, entry.key)
+	ic.size -= entry.size
+}
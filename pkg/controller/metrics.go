@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsNamespace is the prefix of every metric exposed by the controller
+const metricsNamespace = "api"
+
+var (
+	cacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "comic_cache",
+		Name:      "hits_total",
+		Help:      "Number of comics that were served from the in-memory cache.",
+	})
+	cacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "comic_cache",
+		Name:      "misses_total",
+		Help:      "Number of comics that were not found in the in-memory cache.",
+	})
+	cacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "comic_cache",
+		Name:      "evictions_total",
+		Help:      "Number of comics that were evicted from the in-memory cache, either for space or expiry.",
+	})
+	upstreamRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "upstream",
+		Name:      "retries_total",
+		Help:      "Number of requests to the upstream server that were retried, by the status code or error that caused the retry.",
+	}, []string{"reason"})
+	coalescedFetches = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "upstream",
+		Name:      "coalesced_fetches_total",
+		Help:      "Number of comic fetches that joined an identical fetch already in flight instead of reaching the source.",
+	})
+	upstreamBreakerState = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "upstream",
+		Name:      "breaker_state",
+		Help:      "State of the upstream circuit breaker, 0 for closed, 1 for half-open and 2 for open.",
+	})
+)
+
+// RegisterMetrics registers the metrics of the controller with the given registerer, ignoring the
+// ones that have already been registered
+func RegisterMetrics(reg prometheus.Registerer) error {
+	collectors := []prometheus.Collector{
+		cacheHits,
+		cacheMisses,
+		cacheEvictions,
+		upstreamRetries,
+		coalescedFetches,
+		upstreamBreakerState,
+	}
+	for _, collector := range collectors {
+		if err := reg.Register(collector); err != nil {
+			var registered prometheus.AlreadyRegisteredError
+			if !errors.As(err, &registered) {
+				return err
+			}
+		}
+	}
+	return nil
+}
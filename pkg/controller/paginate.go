@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageParameters are the query parameters that select a page, as opposed to the ones selecting the comics
+var pageParameters = map[string]bool{"limit": true, "offset": true, "cursor": true}
+
+// errInvalidCursor is returned when the cursor was not issued for the same query
+var errInvalidCursor = errors.New("please make sure that 'cursor' is a value returned by a previous request with the same parameters")
+
+// page is a window over the list of comics
+type page struct {
+	Offset int
+	Limit  int
+	// query is the fingerprint of the parameters selecting the comics, so that a cursor cannot be
+	// reused with another query where its offset would be meaningless
+	query uint32
+}
+
+// cursor is the decoded representation of an opaque cursor
+type cursor struct {
+	Offset int    `json:"o"`
+	Query  uint32 `json:"q"`
+}
+
+// isPaginated returns whether the request selects a page through any of the page parameters
+func isPaginated(c *gin.Context) bool {
+	for param := range c.Request.URL.Query() {
+		if pageParameters[param] {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePage builds the page described by the 'limit' query parameter, and either the 'offset' or the
+// 'cursor' query parameters. The limit defaults to, and is capped at, maxSize unless it is zero.
+func parsePage(c *gin.Context, maxSize int) (page, error) {
+	p := page{Limit: maxSize, query: queryFingerprint(c.Request.URL.Query())}
+
+	limit, ok, err := getOptionalInt(c, "limit")
+	if err != nil {
+		return page{}, err
+	}
+	if ok {
+		if limit < 1 {
+			return page{}, errors.New("please make sure that 'limit' is greater than 0")
+		}
+		if maxSize <= 0 || limit < maxSize {
+			p.Limit = limit
+		}
+	}
+
+	offset, hasOffset, err := getOptionalInt(c, "offset")
+	if err != nil {
+		return page{}, err
+	}
+	token := c.Query("cursor")
+	switch {
+	case hasOffset && token != "":
+		return page{}, errors.New("please use either 'offset' or 'cursor', not both")
+	case hasOffset:
+		if offset < 0 {
+			return page{}, errors.New("please make sure that 'offset' is not negative")
+		}
+		p.Offset = offset
+	case token != "":
+		if p.Offset, err = p.decodeCursor(token); err != nil {
+			return page{}, err
+		}
+	}
+	return p, nil
+}
+
+// Apply returns the comics within the page
+func (p page) Apply(comics []Comic) []Comic {
+	if p.Offset >= len(comics) {
+		return []Comic{}
+	}
+	comics = comics[p.Offset:]
+	if p.Limit > 0 && p.Limit < len(comics) {
+		comics = comics[:p.Limit]
+	}
+	return comics
+}
+
+// Next returns the cursor of the page following this one, or the empty string if this is the last one
+func (p page) Next(total int) string {
+	if p.Limit <= 0 || p.Offset+p.Limit >= total {
+		return ""
+	}
+	return p.encodeCursor(p.Offset + p.Limit)
+}
+
+// Prev returns the cursor of the page preceding this one, or the empty string if this is the first one
+func (p page) Prev() string {
+	if p.Offset == 0 {
+		return ""
+	}
+	prev := 0
+	if p.Limit > 0 && p.Offset > p.Limit {
+		prev = p.Offset - p.Limit
+	}
+	return p.encodeCursor(prev)
+}
+
+// Links returns the RFC 8288 'Link' header value pointing to the first, previous and next pages of the
+// given request URL, or the empty string if there is a single page
+func (p page) Links(u *url.URL, total int) string {
+	var links []string
+	add := func(token, rel string) {
+		link := *u
+		query := link.Query()
+		query.Del("offset")
+		query.Del("cursor")
+		if token != "" {
+			query.Set("cursor", token)
+		}
+		link.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link.RequestURI(), rel))
+	}
+
+	if p.Offset > 0 {
+		add("", "first")
+		add(p.Prev(), "prev")
+	}
+	if next := p.Next(total); next != "" {
+		add(next, "next")
+	}
+	return strings.Join(links, ", ")
+}
+
+// encodeCursor returns the opaque cursor pointing to the given offset of the query
+func (p page) encodeCursor(offset int) string {
+	b, _ := json.Marshal(cursor{Offset: offset, Query: p.query})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns the offset that the cursor points to, provided that it was issued for the same query
+func (p page) decodeCursor(token string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	var cur cursor
+	if err := json.Unmarshal(b, &cur); err != nil || cur.Offset < 0 || cur.Query != p.query {
+		return 0, errInvalidCursor
+	}
+	return cur.Offset, nil
+}
+
+// queryFingerprint returns a hash of the query parameters, ignoring the ones selecting the page
+func queryFingerprint(query url.Values) uint32 {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if !pageParameters[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	h := fnv.New32a()
+	for _, key := range keys {
+		for _, value := range query[key] {
+			h.Write([]byte(strconv.Quote(key) + "=" + strconv.Quote(value) + "&"))
+		}
+	}
+	return h.Sum32()
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type PaginateUnitSuite struct {
+	suite.Suite
+	comics []Comic
+}
+
+func TestPaginateUnitSuite(t *testing.T) {
+	suite.Run(t, &PaginateUnitSuite{})
+}
+
+func (us *PaginateUnitSuite) SetupSuite() {
+	for num := 1; num <= 5; num++ {
+		us.comics = append(us.comics, Comic{Num: num})
+	}
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *PaginateUnitSuite) TestParsePage() {
+	testCases := []struct {
+		name           string
+		query          string
+		maxSize        int
+		expectedOutput []int
+		expectedNext   bool
+	}{
+		{"Default Page Size", "", 2, []int{1, 2}, true},
+		{"Unbounded Pages", "", 0, []int{1, 2, 3, 4, 5}, false},
+		{"Limit", "?limit=3", 10, []int{1, 2, 3}, true},
+		{"Limit Above Maximum", "?limit=50", 4, []int{1, 2, 3, 4}, true},
+		{"Offset", "?limit=2&offset=2", 10, []int{3, 4}, true},
+		{"Last Page", "?limit=2&offset=4", 10, []int{5}, false},
+		{"Offset Past The End", "?offset=10", 10, []int{}, false},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			p, err := parsePage(newFilterContext(test.query), test.maxSize)
+			us.Nil(err)
+
+			nums := []int{}
+			for _, comic := range p.Apply(us.comics) {
+				nums = append(nums, comic.Num)
+			}
+			us.Equal(test.expectedOutput, nums)
+			us.Equal(test.expectedNext, p.Next(len(us.comics)) != "")
+		})
+	}
+}
+
+func (us *PaginateUnitSuite) TestIsPaginated() {
+	us.False(isPaginated(newFilterContext("?start=1&end=5")))
+	us.True(isPaginated(newFilterContext("?start=1&end=5&limit=2")))
+	us.True(isPaginated(newFilterContext("?offset=0")))
+	us.True(isPaginated(newFilterContext("?cursor=abc")))
+}
+
+func (us *PaginateUnitSuite) TestCursor() {
+	p, err := parsePage(newFilterContext("?start=1&end=5&limit=2"), 10)
+	us.Nil(err)
+
+	// The cursor of the next page points right after the current one, whatever the limit
+	next, err := parsePage(newFilterContext("?start=1&end=5&limit=3&cursor="+p.Next(5)), 10)
+	us.Nil(err)
+	us.Equal(2, next.Offset)
+	us.Equal(3, next.Limit)
+
+	// The previous page of an offset smaller than the limit is the first one
+	prev, err := parsePage(newFilterContext("?start=1&end=5&limit=3&cursor="+next.Prev()), 10)
+	us.Nil(err)
+	us.Equal(0, prev.Offset)
+
+	// The order of the parameters does not matter, but their values do
+	_, err = parsePage(newFilterContext("?end=5&start=1&cursor="+p.Next(5)), 10)
+	us.Nil(err)
+	_, err = parsePage(newFilterContext("?start=2&end=5&cursor="+p.Next(5)), 10)
+	us.Equal(errInvalidCursor, err)
+}
+
+func (us *PaginateUnitSuite) TestLinks() {
+	u, err := url.Parse("/comics?start=1&end=5&limit=2&offset=2")
+	us.Nil(err)
+	p, err := parsePage(newFilterContext("?start=1&end=5&limit=2&offset=2"), 10)
+	us.Nil(err)
+
+	us.Equal(
+		`</comics?end=5&limit=2&start=1>; rel="first", `+
+			`</comics?cursor=`+p.Prev()+`&end=5&limit=2&start=1>; rel="prev", `+
+			`</comics?cursor=`+p.Next(5)+`&end=5&limit=2&start=1>; rel="next"`,
+		p.Links(u, 5),
+	)
+	us.Equal("", page{Limit: 10}.Links(u, 5))
+}
+
+func (us *PaginateUnitSuite) TestParsePageValidation() {
+	testCases := []struct {
+		name          string
+		query         string
+		expectedError error
+	}{
+		{"Invalid Limit", "?limit=ten", errors.New("please make sure that 'limit' is an integer")},
+		{"Zero Limit", "?limit=0", errors.New("please make sure that 'limit' is greater than 0")},
+		{"Negative Offset", "?offset=-1", errors.New("please make sure that 'offset' is not negative")},
+		{"Offset And Cursor", "?offset=1&cursor=abc", errors.New("please use either 'offset' or 'cursor', not both")},
+		{"Malformed Cursor", "?cursor=!!!", errInvalidCursor},
+		{"Forged Cursor", "?cursor=e30", errInvalidCursor},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			_, err := parsePage(newFilterContext(test.query), 10)
+			us.Equal(test.expectedError, err)
+		})
+	}
+}
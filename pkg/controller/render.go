@@ -0,0 +1,284 @@
+package controller
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"github.com/itsemre/go-api-k8s/pkg/httputil"
+)
+
+// errNotRepresentable is returned when an object cannot be rendered in the requested format
+var errNotRepresentable = errors.New("this response cannot be represented in the requested format")
+
+// responseFormat is a representation in which the responses can be rendered
+type responseFormat struct {
+	// name is the value of the 'format' query parameter selecting the format
+	name string
+	// mediaTypes are the media types of the 'Accept' header selecting the format, the first one being
+	// the preferred one
+	mediaTypes []string
+	// renderer returns the gin renderer of the given object for the request
+	renderer func(c *gin.Context, obj any) (render.Render, error)
+}
+
+// responseFormats are the formats that every endpoint can respond with, JSON being the default one
+var responseFormats = []responseFormat{
+	{
+		name:       "json",
+		mediaTypes: []string{gin.MIMEJSON},
+		renderer: func(c *gin.Context, obj any) (render.Render, error) {
+			return render.JSON{Data: obj}, nil
+		},
+	},
+	{
+		name:       "csv",
+		mediaTypes: []string{"text/csv"},
+		renderer:   newCSVRender,
+	},
+	{
+		name:       "xml",
+		mediaTypes: []string{gin.MIMEXML, gin.MIMEXML2},
+		renderer: func(c *gin.Context, obj any) (render.Render, error) {
+			return render.XML{Data: obj}, nil
+		},
+	},
+	{
+		name:       "yaml",
+		mediaTypes: []string{"application/yaml", gin.MIMEYAML, "text/yaml"},
+		renderer: func(c *gin.Context, obj any) (render.Render, error) {
+			return render.YAML{Data: obj}, nil
+		},
+	},
+}
+
+// renderResponse writes the object with the given status code in the format requested by the 'format'
+// query parameter, or negotiated from the 'Accept' header otherwise. Objects that cannot be rendered in
+// the requested format fail the request with a JSON error instead.
+func renderResponse(c *gin.Context, status int, obj any) {
+	format, err := getResponseFormat(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r, err := format.renderer(c, obj)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errNotRepresentable) {
+			status = http.StatusNotAcceptable
+		}
+		_ = c.Error(err)
+		c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if status == http.StatusOK {
+		setLastModified(c, responseComics(obj)...)
+	}
+	c.Render(status, r)
+}
+
+// responseComics returns the comics held by the body of a response
+func responseComics(obj any) []Comic {
+	switch v := obj.(type) {
+	case Comic:
+		return []Comic{v}
+	case comicsResponse:
+		return v.Comics
+	case searchResponse:
+		comics := make([]Comic, len(v.Results))
+		for i, result := range v.Results {
+			comics[i] = result.Comic
+		}
+		return comics
+	}
+	return nil
+}
+
+// setLastModified sets the 'Last-Modified' header of the response to the publication date of the most
+// recent of the comics, if any of them has one
+func setLastModified(c *gin.Context, comics ...Comic) {
+	var latest time.Time
+	for _, comic := range comics {
+		if comic.Published != nil && comic.Published.After(latest) {
+			latest = comic.Published.Time
+		}
+	}
+	if !latest.IsZero() {
+		c.Header("Last-Modified", latest.UTC().Format(http.TimeFormat))
+	}
+}
+
+// getResponseFormat returns the format requested by the 'format' query parameter, or negotiated from
+// the 'Accept' header otherwise. Clients accepting none of the formats are served JSON.
+func getResponseFormat(c *gin.Context) (responseFormat, error) {
+	if name := c.Query("format"); name != "" {
+		for _, format := range responseFormats {
+			if format.name == name {
+				return format, nil
+			}
+		}
+		return responseFormat{}, errors.New("please make sure that 'format' is one of 'json', 'csv', 'xml' or 'yaml'")
+	}
+
+	httputil.AddVary(c.Writer.Header(), "Accept")
+	var offered []string
+	for _, format := range responseFormats {
+		offered = append(offered, format.mediaTypes...)
+	}
+	mediaType := negotiateMediaType(c.GetHeader("Accept"), offered...)
+	for _, format := range responseFormats {
+		for _, t := range format.mediaTypes {
+			if t == mediaType {
+				return format, nil
+			}
+		}
+	}
+	return responseFormats[0], nil
+}
+
+// negotiateMediaType returns the offered media type that is preferred by the 'Accept' header, honoring
+// the quality values and wildcards, or the empty string if none of them is acceptable. Ties are broken
+// by the order of the header, then by the order of the offered types.
+func negotiateMediaType(accept string, offered ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	var (
+		best    string
+		quality float64
+	)
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, param := range params[1:] {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q <= quality {
+			continue
+		}
+		for _, t := range offered {
+			if matchesMediaRange(mediaRange, t) {
+				best, quality = t, q
+				break
+			}
+		}
+	}
+	return best
+}
+
+// matchesMediaRange returns whether the media type belongs to the media range, e.g. 'text/*'
+func matchesMediaRange(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// comicColumns are the CSV columns of a comic, in the default order
+var comicColumns = []string{"num", "title", "safe_title", "day", "month", "year", "transcript", "img", "alt", "news", "link", "published"}
+
+// comicFields returns the values of the CSV columns of a comic
+var comicFields = map[string]func(Comic) string{
+	"num":        func(comic Comic) string { return strconv.Itoa(comic.Num) },
+	"title":      func(comic Comic) string { return comic.Title },
+	"safe_title": func(comic Comic) string { return comic.SafeTitle },
+	"day":        func(comic Comic) string { return comic.Day },
+	"month":      func(comic Comic) string { return comic.Month },
+	"year":       func(comic Comic) string { return comic.Year },
+	"transcript": func(comic Comic) string { return comic.Transcript },
+	"img":        func(comic Comic) string { return comic.Img },
+	"alt":        func(comic Comic) string { return comic.Alt },
+	"news":       func(comic Comic) string { return comic.News },
+	"link":       func(comic Comic) string { return comic.Link },
+	"published": func(comic Comic) string {
+		if comic.Published == nil {
+			return ""
+		}
+		return comic.Published.String()
+	},
+}
+
+// csvRender renders a table as CSV, the first row being the header
+type csvRender struct {
+	rows [][]string
+}
+
+// newCSVRender returns the CSV renderer of the given object. Comics are rendered one per row, with the
+// columns selected by the comma separated 'columns' query parameter, transcripts are rendered one
+// element per row, and flat objects such as errors are rendered as a single row.
+func newCSVRender(c *gin.Context, obj any) (render.Render, error) {
+	var comics []Comic
+	switch v := obj.(type) {
+	case Comic:
+		comics = []Comic{v}
+	case []Comic:
+		comics = v
+	case comicsResponse:
+		comics = v.Comics
+	case transcriptResponse:
+		return csvRender{rows: transcriptRows(v)}, nil
+	case gin.H:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		row := make([]string, len(keys))
+		for i, key := range keys {
+			row[i] = fmt.Sprint(v[key])
+		}
+		return csvRender{rows: [][]string{keys, row}}, nil
+	default:
+		return nil, errNotRepresentable
+	}
+
+	columns := comicColumns
+	if selected := c.Query("columns"); selected != "" {
+		columns = strings.Split(selected, ",")
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+			if _, ok := comicFields[columns[i]]; !ok {
+				return nil, fmt.Errorf("please make sure that 'columns' only contains '%s'", strings.Join(comicColumns, "', '"))
+			}
+		}
+	}
+
+	rows := [][]string{columns}
+	for _, comic := range comics {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = comicFields[column](comic)
+		}
+		rows = append(rows, row)
+	}
+	return csvRender{rows: rows}, nil
+}
+
+// Render writes the table into the response
+func (r csvRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	writer := csv.NewWriter(w)
+	return writer.WriteAll(r.rows)
+}
+
+// WriteContentType writes the CSV content type into the response
+func (r csvRender) WriteContentType(w http.ResponseWriter) {
+	if header := w.Header(); header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/csv; charset=utf-8")
+	}
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type RenderUnitSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func TestRenderUnitSuite(t *testing.T) {
+	suite.Run(t, &RenderUnitSuite{})
+}
+
+func (us *RenderUnitSuite) SetupSuite() {
+	controller := NewController(config.NewConfig(), NewFSSource("testdata"), nil)
+
+	us.router = gin.New()
+	us.router.GET("/comics", controller.GetComics)
+	us.router.GET("/comics/:num", controller.GetComic)
+	us.router.GET("/ping", controller.Health)
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *RenderUnitSuite) TestNegotiateMediaType() {
+	offered := []string{"application/json", "text/csv", "application/xml", "text/xml"}
+	testCases := []struct {
+		name           string
+		accept         string
+		expectedOutput string
+	}{
+		{"No Header", "", "application/json"},
+		{"Exact Match", "text/csv", "text/csv"},
+		{"Wildcard", "*/*", "application/json"},
+		{"Type Wildcard", "text/*", "text/csv"},
+		{"Quality Values", "text/csv;q=0.5, application/xml", "application/xml"},
+		{"Header Order Breaks Ties", "text/xml, text/csv", "text/xml"},
+		{"Case Insensitive", "Text/CSV", "text/csv"},
+		{"Not Acceptable", "image/png", ""},
+		{"Refused", "text/csv;q=0", ""},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			us.Equal(test.expectedOutput, negotiateMediaType(test.accept, offered...))
+		})
+	}
+}
+
+func (us *RenderUnitSuite) TestFormats() {
+	testCases := []struct {
+		name                string
+		path                string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			"CSV Columns",
+			"/comics?start=40&end=42&columns=num,title",
+			"text/csv",
+			200,
+			"text/csv; charset=utf-8",
+			"num,title\n42,Geico\n40,Light\n41,Old Drawing\n",
+		},
+		{
+			"CSV Format Override",
+			"/comics/40?format=csv&columns=num,%20alt",
+			"application/json",
+			200,
+			"text/csv; charset=utf-8",
+			"num,alt\n40,Like a beacon\n",
+		},
+		{
+			"CSV Error",
+			"/comics/43?format=csv",
+			"",
+			404,
+			"text/csv; charset=utf-8",
+			"error\ntestdata/43/info.0.json: comic not found\n",
+		},
+		{
+			"XML",
+			"/comics?start=40&end=42&sort=num&month_parity=even",
+			"application/xml",
+			200,
+			"application/xml; charset=utf-8",
+			`<comics total="0"></comics>`,
+		},
+		{
+			"YAML",
+			"/ping",
+			"application/yaml",
+			200,
+			"application/x-yaml; charset=utf-8",
+			"message: pong\n",
+		},
+		{
+			"Unknown Columns",
+			"/comics/40?format=csv&columns=num,rating",
+			"",
+			400,
+			"application/json; charset=utf-8",
+			`{"error":"please make sure that 'columns' only contains 'num', 'title', 'safe_title', 'day', 'month', 'year', 'transcript', 'img', 'alt', 'news', 'link', 'published'"}`,
+		},
+		{
+			"Unknown Format",
+			"/comics/40?format=pdf",
+			"",
+			400,
+			"application/json; charset=utf-8",
+			`{"error":"please make sure that 'format' is one of 'json', 'csv', 'xml' or 'yaml'"}`,
+		},
+		{
+			"Unacceptable Defaults To JSON",
+			"/ping",
+			"image/png",
+			200,
+			"application/json; charset=utf-8",
+			`{"message":"pong"}`,
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, test.path, nil)
+			us.Nil(err)
+			request.Header.Set("Accept", test.accept)
+
+			us.router.ServeHTTP(recorder, request)
+			us.Equal(test.expectedStatus, recorder.Code)
+			us.Equal(test.expectedContentType, recorder.Header().Get("Content-Type"))
+			us.Equal(test.expectedBody, recorder.Body.String())
+		})
+	}
+}
+
+func (us *RenderUnitSuite) TestXMLComic() {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/comics?start=42&end=42&format=xml", nil)
+	us.Nil(err)
+	us.router.ServeHTTP(recorder, request)
+
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Contains(recorder.Body.String(), `<comics total="1"><comic><num>42</num><title>Geico</title>`)
+}
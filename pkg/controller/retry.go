@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/itsemre/go-api-k8s/pkg/config"
+)
+
+// UpstreamError is returned when the upstream server responds with an unexpected status code
+type UpstreamError struct {
+	URL        string
+	StatusCode int
+	// RetryAfter is the delay requested by the upstream server through the 'Retry-After' header, if any
+	RetryAfter time.Duration
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("upstream responded to %s with status %d", e.URL, e.StatusCode)
+}
+
+// Unwrap makes a 'Not Found' response from upstream match ErrComicNotFound
+func (e *UpstreamError) Unwrap() error {
+	if e.StatusCode == http.StatusNotFound {
+		return ErrComicNotFound
+	}
+	return nil
+}
+
+// RetryPolicy describes how the failed requests made to the upstream server are retried
+type RetryPolicy struct {
+	MaxAttempts       int
+	BaseDelay         time.Duration
+	MaxDelay          time.Duration
+	Jitter            bool
+	RetryableStatuses map[int]bool
+}
+
+// NewRetryPolicy returns the RetryPolicy described by the configuration. Retryable status codes that
+// are not integers are ignored.
+func NewRetryPolicy(conf *config.Config) RetryPolicy {
+	statuses := make(map[int]bool, len(conf.UpstreamRetryStatuses))
+	for _, status := range conf.UpstreamRetryStatuses {
+		if code, err := strconv.Atoi(status); err == nil {
+			statuses[code] = true
+		}
+	}
+	return RetryPolicy{
+		MaxAttempts:       conf.UpstreamRetryAttempts,
+		BaseDelay:         time.Duration(conf.UpstreamRetryBaseDelay) * time.Millisecond,
+		MaxDelay:          time.Duration(conf.UpstreamRetryMaxDelay) * time.Millisecond,
+		Jitter:            conf.UpstreamRetryJitter,
+		RetryableStatuses: statuses,
+	}
+}
+
+// Retryable returns whether a request that failed with the given error may succeed if it is retried
+func (p RetryPolicy) Retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return p.RetryableStatuses[upstreamErr.StatusCode]
+	}
+	// Transport errors, including the timeout of a single attempt, are worth another try
+	return true
+}
+
+// Backoff returns the delay before the given retry, starting from 1. The delay grows exponentially
+// from the base delay up to the maximum one, and when jitter is enabled it is randomized within its
+// upper half so that concurrent clients do not retry in lockstep.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter && delay > 1 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	return delay
+}
+
+// parseRetryAfter parses the value of a 'Retry-After' header, which is either a number of seconds or
+// an HTTP date, into a delay
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type RetryUnitSuite struct {
+	suite.Suite
+	policy RetryPolicy
+}
+
+func TestRetryUnitSuite(t *testing.T) {
+	suite.Run(t, &RetryUnitSuite{})
+}
+
+func (us *RetryUnitSuite) SetupTest() {
+	us.policy = RetryPolicy{
+		MaxAttempts:       3,
+		BaseDelay:         time.Millisecond,
+		MaxDelay:          50 * time.Millisecond,
+		RetryableStatuses: map[int]bool{429: true, 502: true, 503: true, 504: true},
+	}
+}
+
+// newFlakyUpstream returns a fake upstream server that responds to the first 'failures' requests
+// with the given status and header, and with a valid comic afterwards
+func (us *RetryUnitSuite) newFlakyUpstream(failures int32, status int, header http.Header) (*httptest.Server, *int32) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			for key := range header {
+				w.Header().Set(key, header.Get(key))
+			}
+			w.WriteHeader(status)
+			return
+		}
+		fmt.Fprint(w, `{"num": 42, "title": "Geico"}`)
+	}))
+	return upstream, &calls
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *RetryUnitSuite) TestRetries() {
+	testCases := []struct {
+		name            string
+		failures        int32
+		status          int
+		header          http.Header
+		expectedCalls   int32
+		expectedSuccess bool
+	}{
+		{"No Failure", 0, http.StatusOK, nil, 1, true},
+		{"Recovers After Retries", 2, http.StatusServiceUnavailable, nil, 3, true},
+		{"Attempts Exhausted", 5, http.StatusBadGateway, nil, 3, false},
+		{"Non Retryable Status", 1, http.StatusBadRequest, nil, 1, false},
+		{"Short Retry After", 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}, 2, true},
+		{"Retry After Too Long", 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"120"}}, 1, false},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			upstream, calls := us.newFlakyUpstream(test.failures, test.status, test.header)
+			defer upstream.Close()
+			source := &HTTPSource{BaseURL: upstream.URL, Client: upstream.Client(), Retry: us.policy}
+			retries := testutil.ToFloat64(upstreamRetries.WithLabelValues(fmt.Sprint(test.status)))
+
+			comic, err := source.GetComic(context.Background(), 42)
+			us.Equal(test.expectedCalls, atomic.LoadInt32(calls))
+			us.Equal(float64(test.expectedCalls-1), testutil.ToFloat64(upstreamRetries.WithLabelValues(fmt.Sprint(test.status)))-retries)
+			if test.expectedSuccess {
+				us.Nil(err)
+				us.Equal("Geico", comic.Title)
+			} else {
+				var upstreamErr *UpstreamError
+				us.ErrorAs(err, &upstreamErr)
+				us.Equal(test.status, upstreamErr.StatusCode)
+			}
+		})
+	}
+}
+
+func (us *RetryUnitSuite) TestRetryCancelled() {
+	upstream, calls := us.newFlakyUpstream(10, http.StatusServiceUnavailable, nil)
+	defer upstream.Close()
+	us.policy.BaseDelay = time.Hour
+	us.policy.MaxDelay = time.Hour
+	source := &HTTPSource{BaseURL: upstream.URL, Client: upstream.Client(), Retry: us.policy}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := source.GetComic(ctx, 42)
+	us.ErrorIs(err, context.DeadlineExceeded)
+	us.Equal(int32(1), atomic.LoadInt32(calls))
+}
+
+func (us *RetryUnitSuite) TestBackoff() {
+	us.Equal(time.Millisecond, us.policy.Backoff(1))
+	us.Equal(2*time.Millisecond, us.policy.Backoff(2))
+	us.Equal(8*time.Millisecond, us.policy.Backoff(4))
+	us.Equal(50*time.Millisecond, us.policy.Backoff(100))
+
+	us.policy.Jitter = true
+	for retry := 1; retry < 10; retry++ {
+		delay := us.policy.Backoff(retry)
+		us.LessOrEqual(delay, us.policy.MaxDelay)
+		us.GreaterOrEqual(delay, time.Millisecond/2)
+	}
+}
+
+func (us *RetryUnitSuite) TestParseRetryAfter() {
+	now := time.Date(2015, time.October, 21, 7, 28, 0, 0, time.UTC)
+	testCases := []struct {
+		name          string
+		input         string
+		expectedDelay time.Duration
+		expectedOk    bool
+	}{
+		{"Seconds", "120", 2 * time.Minute, true},
+		{"HTTP Date", "Wed, 21 Oct 2015 07:28:30 GMT", 30 * time.Second, true},
+		{"Date In The Past", "Wed, 21 Oct 2015 07:00:00 GMT", 0, true},
+		{"Missing", "", 0, false},
+		{"Invalid", "soon", 0, false},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			delay, ok := parseRetryAfter(test.input, now)
+			us.Equal(test.expectedDelay, delay)
+			us.Equal(test.expectedOk, ok)
+		})
+	}
+}
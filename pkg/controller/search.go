@@ -0,0 +1,414 @@
+package controller
+
+import (
+	"encoding/xml"
+	"errors"
+	"html"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// bm25K1 and bm25B are the term frequency saturation and the document length normalization of BM25
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// fieldGap is the number of positions in between two fields of a document, so that phrases
+	// cannot span across them
+	fieldGap = 100
+
+	// snippetLength is the maximum number of words in a snippet, and snippetContext is the number of
+	// words that precede the first match
+	snippetLength  = 24
+	snippetContext = 6
+)
+
+// phrasePattern matches the quoted phrases of a query
+var phrasePattern = regexp.MustCompile(`"([^"]*)"`)
+
+// searchField is a field of a comic that is searchable
+type searchField struct {
+	name   string
+	weight float64
+	value  func(Comic) string
+}
+
+// searchFields are the fields of a comic that are indexed, the matches in the title weighing more
+var searchFields = []searchField{
+	{"title", 3, func(comic Comic) string { return comic.Title }},
+	{"safe_title", 3, func(comic Comic) string {
+		// The safe title is usually the same as the title, which should not count twice
+		if comic.SafeTitle == comic.Title {
+			return ""
+		}
+		return comic.SafeTitle
+	}},
+	{"alt", 1.5, func(comic Comic) string { return comic.Alt }},
+	{"transcript", 1, func(comic Comic) string { return comic.Transcript }},
+}
+
+// posting is the occurrences of a term in a document
+type posting struct {
+	// frequency is the number of occurrences, weighted by the fields they are in
+	frequency float64
+	// positions are the ascending positions of the occurrences
+	positions []int
+}
+
+// searchDocument is an indexed comic
+type searchDocument struct {
+	comic  Comic
+	length float64
+	terms  []string
+}
+
+// SearchIndex is an in-memory inverted index of the comics, ranking them with BM25
+type SearchIndex struct {
+	mu        sync.RWMutex
+	documents map[int]*searchDocument
+	postings  map[string]map[int]*posting
+	// totalLength is the sum of the lengths of the documents
+	totalLength float64
+}
+
+// NewSearchIndex returns a pointer to a new, empty, SearchIndex instance
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		documents: make(map[int]*searchDocument),
+		postings:  make(map[string]map[int]*posting),
+	}
+}
+
+// Add indexes the given comics, replacing the previous version of the ones that were already indexed
+func (idx *SearchIndex) Add(comics ...Comic) {
+	if idx == nil {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, comic := range comics {
+		if doc, ok := idx.documents[comic.Num]; ok {
+			if sameSearchFields(doc.comic, comic) {
+				doc.comic = comic
+				continue
+			}
+			idx.remove(comic.Num)
+		}
+
+		doc := &searchDocument{comic: comic}
+		position := 0
+		for _, field := range searchFields {
+			for _, tok := range tokenize(field.value(comic)) {
+				position++
+				if tok.stop {
+					continue
+				}
+				postings, ok := idx.postings[tok.term]
+				if !ok {
+					postings = make(map[int]*posting)
+					idx.postings[tok.term] = postings
+				}
+				p, ok := postings[comic.Num]
+				if !ok {
+					p = &posting{}
+					postings[comic.Num] = p
+					doc.terms = append(doc.terms, tok.term)
+				}
+				p.frequency += field.weight
+				p.positions = append(p.positions, position)
+				doc.length += field.weight
+			}
+			position += fieldGap
+		}
+		idx.documents[comic.Num] = doc
+		idx.totalLength += doc.length
+	}
+}
+
+// Len returns the number of indexed comics
+func (idx *SearchIndex) Len() int {
+	if idx == nil {
+		return 0
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.documents)
+}
+
+// remove unindexes the comic with the given number, the index must be locked
+func (idx *SearchIndex) remove(num int) {
+	doc := idx.documents[num]
+	for _, term := range doc.terms {
+		delete(idx.postings[term], num)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLength -= doc.length
+	delete(idx.documents, num)
+}
+
+// searchQuery is a parsed search query
+type searchQuery struct {
+	// terms are the indexed terms of the query, including the ones of its phrases
+	terms []string
+	// phrases are the terms of the quoted phrases, by their offset within the phrase
+	phrases []map[int]string
+}
+
+// parseSearchQuery extracts the terms and the quoted phrases of the query
+func parseSearchQuery(query string) searchQuery {
+	var q searchQuery
+	seen := make(map[string]bool)
+	addTerm := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			q.terms = append(q.terms, term)
+		}
+	}
+
+	for _, match := range phrasePattern.FindAllStringSubmatch(query, -1) {
+		phrase := make(map[int]string)
+		for offset, tok := range tokenize(match[1]) {
+			if !tok.stop {
+				phrase[offset] = tok.term
+				addTerm(tok.term)
+			}
+		}
+		if len(phrase) > 0 {
+			q.phrases = append(q.phrases, phrase)
+		}
+	}
+	for _, tok := range tokenize(phrasePattern.ReplaceAllString(query, " ")) {
+		if !tok.stop {
+			addTerm(tok.term)
+		}
+	}
+	return q
+}
+
+// searchHit is a comic matching a search query
+type searchHit struct {
+	comic Comic
+	score float64
+}
+
+// Search returns the comics matching any of the terms of the query and all of its phrases, ranked by
+// their BM25 score
+func (idx *SearchIndex) Search(q searchQuery) []searchHit {
+	if idx == nil || len(q.terms) == 0 {
+		return nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := float64(len(idx.documents))
+	avgLength := idx.totalLength / math.Max(n, 1)
+	scores := make(map[int]float64)
+	for _, term := range q.terms {
+		postings := idx.postings[term]
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for num, p := range postings {
+			norm := 1 - bm25B + bm25B*idx.documents[num].length/math.Max(avgLength, 1)
+			scores[num] += idf * p.frequency * (bm25K1 + 1) / (p.frequency + bm25K1*norm)
+		}
+	}
+
+	hits := make([]searchHit, 0, len(scores))
+	for num, score := range scores {
+		if idx.matchesPhrases(num, q.phrases) {
+			hits = append(hits, searchHit{comic: idx.documents[num].comic, score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].comic.Num < hits[j].comic.Num
+	})
+	return hits
+}
+
+// matchesPhrases returns whether the document contains every phrase, the index must be locked
+func (idx *SearchIndex) matchesPhrases(num int, phrases []map[int]string) bool {
+	for _, phrase := range phrases {
+		// Anchor the phrase on the occurrences of its first term, and check the others relatively to it
+		anchorOffset, anchorTerm := -1, ""
+		for offset, term := range phrase {
+			if anchorOffset < 0 || offset < anchorOffset {
+				anchorOffset, anchorTerm = offset, term
+			}
+		}
+		anchor, ok := idx.postings[anchorTerm][num]
+		if !ok {
+			return false
+		}
+
+		found := false
+		for _, position := range anchor.positions {
+			found = true
+			for offset, term := range phrase {
+				p, ok := idx.postings[term][num]
+				if !ok || !containsPosition(p.positions, position+offset-anchorOffset) {
+					found = false
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// containsPosition returns whether the ascending positions contain the given one
+func containsPosition(positions []int, position int) bool {
+	i := sort.SearchInts(positions, position)
+	return i < len(positions) && positions[i] == position
+}
+
+// sameSearchFields returns whether the searchable fields of both comics are the same
+func sameSearchFields(a, b Comic) bool {
+	for _, field := range searchFields {
+		if field.value(a) != field.value(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// searchSnippet is an excerpt of a field of a comic, with the matching words highlighted
+type searchSnippet struct {
+	Field string `json:"field" xml:"field,attr" yaml:"field"`
+	Text  string `json:"text" xml:",chardata" yaml:"text"`
+}
+
+// searchResult is a comic matching a search query
+type searchResult struct {
+	Comic    Comic           `json:"comic" xml:"comic" yaml:"comic"`
+	Score    float64         `json:"score" xml:"score,attr" yaml:"score"`
+	Snippets []searchSnippet `json:"snippets" xml:"snippet" yaml:"snippets"`
+}
+
+// searchResponse is the body of a successful search
+type searchResponse struct {
+	XMLName    xml.Name       `json:"-" xml:"search" yaml:"-"`
+	Results    []searchResult `json:"results" xml:"result" yaml:"results"`
+	Total      int            `json:"total" xml:"total,attr" yaml:"total"`
+	NextCursor string         `json:"next_cursor,omitempty" xml:"next_cursor,attr,omitempty" yaml:"next_cursor,omitempty"`
+}
+
+// Search is a gin handler function that returns the comics matching the 'q' query parameter, among the
+// ones that are known locally, ranked by relevance. The words of the query are matched regardless of
+// their case, accents and inflections, and the quoted phrases must be matched exactly. The results are
+// paginated as described by parsePage, and come with snippets of the fields that matched.
+func (ctrl *Controller) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		AbortWithError(c, http.StatusBadRequest, errors.New("please include the search query 'q'"))
+		return
+	}
+	pg, err := parsePage(c, ctrl.Cfg.MaxPageSize)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	q := parseSearchQuery(query)
+	hits := ctrl.Index.Search(q)
+
+	// Only the comics of the page are highlighted
+	start := pg.Offset
+	if start > len(hits) {
+		start = len(hits)
+	}
+	end := len(hits)
+	if pg.Limit > 0 && start+pg.Limit < end {
+		end = start + pg.Limit
+	}
+	results := make([]searchResult, 0, end-start)
+	for _, hit := range hits[start:end] {
+		results = append(results, searchResult{
+			Comic:    ctrl.proxyImage(c, hit.comic),
+			Score:    math.Round(hit.score*1000) / 1000,
+			Snippets: highlight(hit.comic, q),
+		})
+	}
+
+	if links := pg.Links(c.Request.URL, len(hits)); links != "" {
+		c.Header("Link", links)
+	}
+	renderResponse(c, http.StatusOK, searchResponse{
+		Results:    results,
+		Total:      len(hits),
+		NextCursor: pg.Next(len(hits)),
+	})
+}
+
+// highlight returns a snippet of every field of the comic that matches the query, the matching words
+// being wrapped in <mark> tags and the rest of the text being HTML escaped
+func highlight(comic Comic, q searchQuery) []searchSnippet {
+	terms := make(map[string]bool, len(q.terms))
+	for _, term := range q.terms {
+		terms[term] = true
+	}
+
+	snippets := []searchSnippet{}
+	for _, field := range searchFields {
+		text := field.value(comic)
+		tokens := tokenize(text)
+		first := -1
+		for i, tok := range tokens {
+			if !tok.stop && terms[tok.term] {
+				first = i
+				break
+			}
+		}
+		if first < 0 {
+			continue
+		}
+
+		from := first - snippetContext
+		if from < 0 {
+			from = 0
+		}
+		to := from + snippetLength
+		if to > len(tokens) {
+			to = len(tokens)
+		}
+
+		var b strings.Builder
+		if from > 0 {
+			b.WriteString("…")
+		}
+		offset := tokens[from].start
+		for _, tok := range tokens[from:to] {
+			b.WriteString(html.EscapeString(text[offset:tok.start]))
+			word := html.EscapeString(text[tok.start:tok.end])
+			if !tok.stop && terms[tok.term] {
+				word = "<mark>" + word + "</mark>"
+			}
+			b.WriteString(word)
+			offset = tok.end
+		}
+		if to < len(tokens) {
+			b.WriteString("…")
+		} else {
+			b.WriteString(html.EscapeString(text[offset:]))
+		}
+		snippets = append(snippets, searchSnippet{Field: field.name, Text: strings.TrimSpace(b.String())})
+	}
+	return snippets
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type SearchUnitSuite struct {
+	suite.Suite
+	comics []Comic
+}
+
+func TestSearchUnitSuite(t *testing.T) {
+	suite.Run(t, &SearchUnitSuite{})
+}
+
+func (us *SearchUnitSuite) SetupSuite() {
+	us.comics = []Comic{
+		{Num: 303, Title: "Compiling", SafeTitle: "Compiling", Alt: "'Are you stealing those LCDs?' 'Yeah, but I'm doing it while my code compiles.'"},
+		{Num: 1205, Title: "Is It Worth the Time?", SafeTitle: "Is It Worth the Time?", Alt: "Don't forget the time you spend finding the chart to look up what you save."},
+		{Num: 927, Title: "Standards", SafeTitle: "Standards", Alt: "Fortunately, the charging one has been solved now that we've all standardized on mini-USB."},
+		{Num: 1190, Title: "Time", SafeTitle: "Time", Transcript: "[[Megan and Cueball are sitting on the sand.]]\nMegan: The sand is warm.\nCueball: It is time to build a castle."},
+	}
+}
+
+// nums returns the numbers of the comics of the hits
+func nums(hits []searchHit) []int {
+	nums := []int{}
+	for _, hit := range hits {
+		nums = append(nums, hit.comic.Num)
+	}
+	return nums
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *SearchUnitSuite) TestSearch() {
+	index := NewSearchIndex()
+	index.Add(us.comics...)
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedOutput []int
+	}{
+		{"Single Term", "standards", []int{927}},
+		{"Inflections", "compiled", []int{303}},
+		{"Title Ranks First", "time", []int{1190, 1205}},
+		{"Any Term", "castle chart", []int{1205, 1190}},
+		{"Case And Accents", "STÁNDARDS", []int{927}},
+		{"Phrase", `"the sand is warm"`, []int{1190}},
+		{"Phrase Out Of Order", `"warm sand"`, []int{}},
+		{"Phrase Across Fields", `"time megan"`, []int{}},
+		{"Phrase And Terms", `"worth the time" castle`, []int{1205}},
+		{"Only Stop Words", "the", []int{}},
+		{"Unknown Term", "velociraptor", []int{}},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			us.Equal(test.expectedOutput, nums(index.Search(parseSearchQuery(test.query))))
+		})
+	}
+}
+
+func (us *SearchUnitSuite) TestIncrementalUpdates() {
+	index := NewSearchIndex()
+	index.Add(us.comics...)
+	us.Equal(4, index.Len())
+
+	// Replacing a comic unindexes its previous version
+	updated := us.comics[2]
+	updated.Alt = "Situation: there are 15 competing standards."
+	index.Add(updated)
+	us.Equal(4, index.Len())
+	us.Equal([]int{}, nums(index.Search(parseSearchQuery("charging"))))
+	us.Equal([]int{927}, nums(index.Search(parseSearchQuery("competing"))))
+
+	// A nil index is disabled
+	var disabled *SearchIndex
+	disabled.Add(us.comics...)
+	us.Equal(0, disabled.Len())
+	us.Nil(disabled.Search(parseSearchQuery("time")))
+}
+
+func (us *SearchUnitSuite) TestHighlight() {
+	snippets := highlight(us.comics[1], parseSearchQuery("time chart"))
+	us.Equal([]searchSnippet{
+		{Field: "title", Text: "Is It Worth the <mark>Time</mark>?"},
+		{Field: "alt", Text: "Don&#39;t forget the <mark>time</mark> you spend finding the <mark>chart</mark> to look up what you save."},
+	}, snippets)
+
+	// Long fields are cut around the first match
+	long := Comic{Transcript: "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty twentyone twentytwo twentythree twentyfour twentyfive twentysix twentyseven twentyeight twentynine thirty"}
+	snippets = highlight(long, parseSearchQuery("ten"))
+	us.Equal([]searchSnippet{
+		{Field: "transcript", Text: "…four five six seven eight nine <mark>ten</mark> eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty twentyone twentytwo twentythree twentyfour twentyfive twentysix twentyseven…"},
+	}, snippets)
+}
+
+func (us *SearchUnitSuite) TestSearchEndpoint() {
+	conf := config.NewConfig()
+	conf.MaxPageSize = 1
+	controller := NewController(conf, NewFSSource("testdata"), nil)
+	controller.Index.Add(us.comics...)
+
+	router := gin.New()
+	router.GET("/search", controller.Search)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/search?q=time", nil)
+	us.Nil(err)
+	router.ServeHTTP(recorder, request)
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Contains(recorder.Header().Get("Link"), `rel="next"`)
+
+	var response struct {
+		Results []struct {
+			Comic    Comic           `json:"comic"`
+			Score    float64         `json:"score"`
+			Snippets []searchSnippet `json:"snippets"`
+		} `json:"results"`
+		Total      int    `json:"total"`
+		NextCursor string `json:"next_cursor"`
+	}
+	us.Nil(json.Unmarshal(recorder.Body.Bytes(), &response))
+	us.Equal(2, response.Total)
+	us.NotEmpty(response.NextCursor)
+	us.Require().Len(response.Results, 1)
+	us.Equal(1190, response.Results[0].Comic.Num)
+	us.Greater(response.Results[0].Score, 0.0)
+	us.Equal("title", response.Results[0].Snippets[0].Field)
+
+	// The comics fetched through the controller become searchable
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/search?q=geico", nil)
+	us.Nil(err)
+	router.ServeHTTP(recorder, request)
+	us.Equal(`{"results":[],"total":0}`, recorder.Body.String())
+
+	_, err = controller.getComic(request.Context(), 42)
+	us.Nil(err)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	us.Contains(recorder.Body.String(), `"total":1`)
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/search?q=%20", nil)
+	us.Nil(err)
+	router.ServeHTTP(recorder, request)
+	us.Equal(http.StatusBadRequest, recorder.Code)
+	us.Equal(`{"error":"please include the search query 'q'"}`, recorder.Body.String())
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sortOrderAsc  = "asc"
+	sortOrderDesc = "desc"
+)
+
+// comicComparator returns a negative number when a sorts before b, a positive number when a sorts
+// after b, and zero when they are equal
+type comicComparator func(a, b Comic) int
+
+// comparators holds the comparator of every key that the comics can be sorted by, except for the
+// titles whose comparators depend on the locale of the request
+var comparators = map[string]comicComparator{
+	"num": func(a, b Comic) int {
+		return a.Num - b.Num
+	},
+	"date": func(a, b Comic) int {
+		if c := compareNumeric(a.Year, b.Year); c != 0 {
+			return c
+		}
+		if c := compareNumeric(a.Month, b.Month); c != 0 {
+			return c
+		}
+		return compareNumeric(a.Day, b.Day)
+	},
+	"year": func(a, b Comic) int {
+		return compareNumeric(a.Year, b.Year)
+	},
+	"month": func(a, b Comic) int {
+		return compareNumeric(a.Month, b.Month)
+	},
+	"day": func(a, b Comic) int {
+		return compareNumeric(a.Day, b.Day)
+	},
+}
+
+// parseSort builds the comparator described by the 'sort' and 'order' query parameters of the request.
+// 'sort' is a comma separated list of keys, the latter ones breaking the ties of the former ones, and
+// defaults to 'title'. The keys are sorted in the direction given by 'order', which defaults to 'asc',
+// except for the ones prefixed by a '-' which are sorted in descending order. Titles are collated
+// according to the locale of the request, see parseCollator.
+func parseSort(c *gin.Context) (comicComparator, error) {
+	order := c.DefaultQuery("order", sortOrderAsc)
+	if order != sortOrderAsc && order != sortOrderDesc {
+		return nil, errors.New("please make sure that 'order' is either 'asc' or 'desc'")
+	}
+	collator, err := parseCollator(c)
+	if err != nil {
+		return nil, err
+	}
+	titleComparators := map[string]comicComparator{
+		"title": func(a, b Comic) int {
+			return collator.Compare(a.Title, b.Title)
+		},
+		"safe_title": func(a, b Comic) int {
+			return collator.Compare(a.SafeTitle, b.SafeTitle)
+		},
+	}
+
+	var keys []comicComparator
+	for _, key := range strings.Split(c.DefaultQuery("sort", "title"), ",") {
+		key = strings.TrimSpace(key)
+		desc := order == sortOrderDesc
+		if strings.HasPrefix(key, "-") {
+			key, desc = key[1:], true
+		}
+		compare, ok := comparators[key]
+		if !ok {
+			compare, ok = titleComparators[key]
+		}
+		if !ok {
+			return nil, errors.New("please make sure that 'sort' only contains 'title', 'safe_title', 'num', 'date', 'year', 'month' or 'day'")
+		}
+		if desc {
+			compare = reverse(compare)
+		}
+		keys = append(keys, compare)
+	}
+
+	return func(a, b Comic) int {
+		for _, compare := range keys {
+			if c := compare(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}, nil
+}
+
+// sortComics sorts the comics with the given comparator, keeping the equal ones in their original order
+func sortComics(comics []Comic, compare comicComparator) {
+	sort.SliceStable(comics, func(i, j int) bool {
+		return compare(comics[i], comics[j]) < 0
+	})
+}
+
+// reverse returns a comparator sorting in the opposite direction of the given one
+func reverse(compare comicComparator) comicComparator {
+	return func(a, b Comic) int {
+		return compare(b, a)
+	}
+}
+
+// compareNumeric compares two numbers stored as strings, the ones that are not numbers sort first
+func compareNumeric(a, b string) int {
+	x, errX := strconv.Atoi(a)
+	y, errY := strconv.Atoi(b)
+	switch {
+	case errX != nil && errY != nil:
+		return strings.Compare(a, b)
+	case errX != nil:
+		return -1
+	case errY != nil:
+		return 1
+	}
+	return x - y
+}
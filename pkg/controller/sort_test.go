@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type SortUnitSuite struct {
+	suite.Suite
+	comics []Comic
+}
+
+func TestSortUnitSuite(t *testing.T) {
+	suite.Run(t, &SortUnitSuite{})
+}
+
+func (us *SortUnitSuite) SetupSuite() {
+	us.comics = []Comic{
+		{Num: 40, Title: "Light", SafeTitle: "Light", Day: "1", Month: "1", Year: "2006"},
+		{Num: 41, Title: "Old Drawing", SafeTitle: "Old Drawing", Day: "1", Month: "1", Year: "2006"},
+		{Num: 42, Title: "Geico", SafeTitle: "Geico", Day: "1", Month: "1", Year: "2006"},
+		{Num: 303, Title: "Compiling", SafeTitle: "Compiling", Day: "15", Month: "8", Year: "2007"},
+		{Num: 1337, Title: "Hofstadter", SafeTitle: "Hofstadter", Day: "9", Month: "3", Year: "2014"},
+		{Num: 1190, Title: "Time", SafeTitle: "Time", Day: "25", Month: "3", Year: "2013"},
+		{Num: 1000, Title: "1000 Comics", SafeTitle: "1000 Comics", Day: "6", Month: "1", Year: "2012"},
+		{Num: 259, Title: "\"Clichéd Exchanges\"", SafeTitle: "Clichd Exchanges", Day: "14", Month: "5", Year: "2007"},
+	}
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *SortUnitSuite) TestParseSort() {
+	testCases := []struct {
+		name           string
+		query          string
+		expectedOutput []int
+	}{
+		{"Title By Default", "", []int{259, 1000, 303, 42, 1337, 40, 41, 1190}},
+		{"Title Descending", "?order=desc", []int{1190, 41, 40, 1337, 42, 303, 1000, 259}},
+		{"Safe Title", "?sort=safe_title", []int{259, 1000, 303, 42, 1337, 40, 41, 1190}},
+		{"Number", "?sort=num", []int{40, 41, 42, 259, 303, 1000, 1190, 1337}},
+		{"Number Descending", "?sort=-num", []int{1337, 1190, 1000, 303, 259, 42, 41, 40}},
+		{"Date Is Stable", "?sort=date", []int{40, 41, 42, 259, 303, 1000, 1190, 1337}},
+		{"Date Descending", "?sort=date&order=desc", []int{1337, 1190, 1000, 303, 259, 40, 41, 42}},
+		{"Multiple Keys", "?sort=year,-num", []int{42, 41, 40, 303, 259, 1000, 1190, 1337}},
+		{"Multiple Keys With Order", "?sort=month,num&order=desc", []int{303, 259, 1337, 1190, 1000, 42, 41, 40}},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			compare, err := parseSort(newFilterContext(test.query))
+			us.Nil(err)
+
+			comics := append([]Comic(nil), us.comics...)
+			sortComics(comics, compare)
+			var nums []int
+			for _, comic := range comics {
+				nums = append(nums, comic.Num)
+			}
+			us.Equal(test.expectedOutput, nums)
+		})
+	}
+}
+
+func (us *SortUnitSuite) TestParseSortValidation() {
+	testCases := []struct {
+		name          string
+		query         string
+		expectedError error
+	}{
+		{"Unknown Key", "?sort=popularity", errors.New("please make sure that 'sort' only contains 'title', 'safe_title', 'num', 'date', 'year', 'month' or 'day'")},
+		{"Empty Key", "?sort=num,", errors.New("please make sure that 'sort' only contains 'title', 'safe_title', 'num', 'date', 'year', 'month' or 'day'")},
+		{"Unknown Order", "?order=random", errors.New("please make sure that 'order' is either 'asc' or 'desc'")},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			_, err := parseSort(newFilterContext(test.query))
+			us.Equal(test.expectedError, err)
+		})
+	}
+}
+
+func (us *SortUnitSuite) TestTitleCollation() {
+	comics := []Comic{
+		{Num: 1, Title: "Zebra"},
+		{Num: 2, Title: "\u00c9clair"},
+		{Num: 3, Title: "E\u0301cole"},
+		{Num: 4, Title: "The Apple"},
+		{Num: 5, Title: "Item 10"},
+		{Num: 6, Title: "Item 9"},
+		{Num: 7, Title: ""},
+		{Num: 8, Title: "\"...\""},
+		{Num: 9, Title: "404"},
+		{Num: 10, Title: "\u00d6sterreich"},
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		acceptLanguage string
+		expectedOutput []int
+	}{
+		{"Default Locale", "", "", []int{7, 8, 9, 2, 3, 6, 5, 10, 4, 1}},
+		{"Ignore Articles", "?ignore_articles=true", "", []int{7, 8, 9, 4, 2, 3, 6, 5, 10, 1}},
+		{"Swedish Locale", "?locale=sv", "", []int{7, 8, 9, 2, 3, 6, 5, 4, 1, 10}},
+		{"Accept Language", "", "sv-SE, en;q=0.5", []int{7, 8, 9, 2, 3, 6, 5, 4, 1, 10}},
+		{"Locale Overrides Accept Language", "?locale=en", "sv", []int{7, 8, 9, 2, 3, 6, 5, 10, 4, 1}},
+		{"Invalid Accept Language", "", ";;;", []int{7, 8, 9, 2, 3, 6, 5, 10, 4, 1}},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			c := newFilterContext(test.query)
+			c.Request.Header.Set("Accept-Language", test.acceptLanguage)
+			compare, err := parseSort(c)
+			us.Nil(err)
+
+			sorted := append([]Comic(nil), comics...)
+			us.NotPanics(func() { sortComics(sorted, compare) })
+			var nums []int
+			for _, comic := range sorted {
+				nums = append(nums, comic.Num)
+			}
+			us.Equal(test.expectedOutput, nums)
+		})
+	}
+}
+
+func (us *SortUnitSuite) TestParseCollatorValidation() {
+	testCases := []struct {
+		name          string
+		query         string
+		expectedError error
+	}{
+		{"Invalid Locale", "?locale=not_a_locale!", errors.New("please make sure that 'locale' is a valid BCP 47 language tag")},
+		{"Invalid Articles Flag", "?ignore_articles=maybe", errors.New("please make sure that 'ignore_articles' is either true or false")},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/comics"+test.query, nil)
+			_, err := parseCollator(c)
+			us.Equal(test.expectedError, err)
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	log "github.com/itsemre/go-api-k8s/pkg/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// metadataFileName is the name of the file holding the metadata of a comic book, both upstream and on disk
+const metadataFileName = "info.0.json"
+
+// maxDownloadSize is the maximum size in bytes of a response from upstream, i.e. of a comic or an image
+const maxDownloadSize = 32 << 20
+
+var (
+	// ErrComicNotFound is returned by a ComicSource when the requested comic does not exist
+	ErrComicNotFound = errors.New("comic not found")
+	// ErrImageNotFound is returned by an ImageSource when the comic has no image
+	ErrImageNotFound = errors.New("image not found")
+)
+
+// ComicSource is the origin from which the metadata of the comic books is retrieved
+type ComicSource interface {
+	// GetComic returns the comic with the given number
+	GetComic(ctx context.Context, num int) (Comic, error)
+	// GetLatest returns the most recently published comic
+	GetLatest(ctx context.Context) (Comic, error)
+}
+
+// ImageSource is the origin from which the images of the comic books are retrieved, which is
+// implemented by the comic sources that are able to serve images
+type ImageSource interface {
+	// GetImage returns the image of the given comic
+	GetImage(ctx context.Context, comic Comic) ([]byte, error)
+}
+
+// NewComicSource returns the ComicSource described by the configuration, i.e. the local directory if
+// one was given, and the upstream HTTP server guarded by a circuit breaker (unless disabled) otherwise
+func NewComicSource(conf *config.Config) ComicSource {
+	if conf.UpstreamDir != "" {
+		return NewFSSource(conf.UpstreamDir)
+	}
+	if conf.BreakerFailureRatio <= 0 {
+		return NewHTTPSource(conf)
+	}
+	breaker := NewCircuitBreaker(
+		conf.BreakerFailureRatio,
+		conf.BreakerMinRequests,
+		time.Duration(conf.BreakerWindow)*time.Second,
+		time.Duration(conf.BreakerCooldown)*time.Second,
+	)
+	return NewBreakerSource(NewHTTPSource(conf), breaker)
+}
+
+// HTTPSource is a ComicSource that retrieves the comics from an xkcd compatible HTTP server, retrying
+// the failed requests according to its RetryPolicy
+type HTTPSource struct {
+	BaseURL string
+	// ImageBaseURL replaces the scheme and the host of the URLs of the images when it is set
+	ImageBaseURL string
+	UserAgent    string
+	Client       *http.Client
+	Retry        RetryPolicy
+}
+
+// NewHTTPSource returns a pointer to a new HTTPSource instance
+func NewHTTPSource(conf *config.Config) *HTTPSource {
+	return &HTTPSource{
+		BaseURL:      strings.TrimSuffix(conf.UpstreamURL, "/"),
+		ImageBaseURL: strings.TrimSuffix(conf.ImageUpstreamURL, "/"),
+		UserAgent:    conf.UpstreamUserAgent,
+		// The timeout of the client applies to every attempt separately
+		Client: &http.Client{
+			Timeout: time.Duration(conf.UpstreamTimeout) * time.Second,
+		},
+		Retry: NewRetryPolicy(conf),
+	}
+}
+
+// GetComic returns the comic with the given number from the upstream server
+func (s *HTTPSource) GetComic(ctx context.Context, num int) (Comic, error) {
+	return s.get(ctx, fmt.Sprintf("%s/%d/%s", s.BaseURL, num, metadataFileName))
+}
+
+// GetLatest returns the most recently published comic from the upstream server
+func (s *HTTPSource) GetLatest(ctx context.Context) (Comic, error) {
+	return s.get(ctx, fmt.Sprintf("%s/%s", s.BaseURL, metadataFileName))
+}
+
+// get performs a GET request on the given URL and decodes the response body into a Comic, retrying
+// the request as long as the error is retryable and the attempts are not exhausted
+func (s *HTTPSource) get(ctx context.Context, url string) (Comic, error) {
+	var comic Comic
+	err := s.retry(ctx, func() error {
+		body, err := s.download(ctx, url)
+		if err != nil {
+			return err
+		}
+		comic, err = parseComic(body)
+		return err
+	})
+	return comic, err
+}
+
+// GetImage returns the image of the comic from the host of its URL, or from ImageBaseURL if it is set
+func (s *HTTPSource) GetImage(ctx context.Context, comic Comic) ([]byte, error) {
+	if comic.Img == "" {
+		return nil, ErrImageNotFound
+	}
+	url, err := rebaseURL(comic.Img, s.ImageBaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var image []byte
+	err = s.retry(ctx, func() error {
+		image, err = s.download(ctx, url)
+		return err
+	})
+	return image, err
+}
+
+// retry makes the given attempt as long as its error is retryable and the attempts are not exhausted
+func (s *HTTPSource) retry(ctx context.Context, attempt func() error) error {
+	for i := 1; ; i++ {
+		err := attempt()
+		if err == nil || i >= s.Retry.MaxAttempts || !s.Retry.Retryable(err) {
+			return err
+		}
+
+		// Wait for as long as the upstream server asked to, unless it is longer than we are willing to
+		reason := "error"
+		delay := s.Retry.Backoff(i)
+		var upstreamErr *UpstreamError
+		if errors.As(err, &upstreamErr) {
+			reason = strconv.Itoa(upstreamErr.StatusCode)
+			if upstreamErr.RetryAfter > s.Retry.MaxDelay {
+				return err
+			}
+			if upstreamErr.RetryAfter > delay {
+				delay = upstreamErr.RetryAfter
+			}
+		}
+		upstreamRetries.WithLabelValues(reason).Inc()
+		log.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+			"attempt": i,
+			"delay":   delay.String(),
+		}).Warn("retrying upstream request")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// download performs a single attempt of a GET request on the given URL and returns the response body,
+// which is limited to maxDownloadSize bytes
+func (s *HTTPSource) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.UserAgent != "" {
+		req.Header.Set("User-Agent", s.UserAgent)
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return nil, &UpstreamError{URL: url, StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxDownloadSize {
+		return nil, fmt.Errorf("the response of upstream to %s is larger than %d bytes", url, maxDownloadSize)
+	}
+	return body, nil
+}
+
+// FSSource is a ComicSource that reads the comics from a local directory laid out like the upstream
+// server, i.e. the metadata of comic N is stored in '<dir>/N/info.0.json'
+type FSSource struct {
+	Dir string
+}
+
+// NewFSSource returns a pointer to a new FSSource instance
+func NewFSSource(dir string) *FSSource {
+	return &FSSource{
+		Dir: dir,
+	}
+}
+
+// GetComic returns the comic with the given number from the directory
+func (s *FSSource) GetComic(ctx context.Context, num int) (Comic, error) {
+	return s.read(filepath.Join(s.Dir, strconv.Itoa(num), metadataFileName))
+}
+
+// GetLatest returns the comic stored in '<dir>/info.0.json' if there is one, and the comic with the
+// highest number in the directory otherwise
+func (s *FSSource) GetLatest(ctx context.Context) (Comic, error) {
+	comic, err := s.read(filepath.Join(s.Dir, metadataFileName))
+	if !errors.Is(err, ErrComicNotFound) {
+		return comic, err
+	}
+
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return comic, err
+	}
+	latest := 0
+	for _, entry := range entries {
+		if num, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() && num > latest {
+			latest = num
+		}
+	}
+	if latest == 0 {
+		return comic, fmt.Errorf("no comics in %s: %w", s.Dir, ErrComicNotFound)
+	}
+	return s.GetComic(ctx, latest)
+}
+
+// GetImage returns the image of the comic from the directory, i.e. the file of comic N named after its
+// URL is read from '<dir>/N/'
+func (s *FSSource) GetImage(ctx context.Context, comic Comic) ([]byte, error) {
+	if comic.Img == "" {
+		return nil, ErrImageNotFound
+	}
+	u, err := url.Parse(comic.Img)
+	if err != nil {
+		return nil, err
+	}
+	image, err := os.ReadFile(filepath.Join(s.Dir, strconv.Itoa(comic.Num), path.Base(u.Path)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrImageNotFound
+	}
+	return image, err
+}
+
+// rebaseURL replaces the scheme and the host of the URL with the ones of the base URL, if there is one
+func rebaseURL(rawURL, baseURL string) (string, error) {
+	if baseURL == "" {
+		return rawURL, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return baseURL + u.EscapedPath(), nil
+}
+
+// read decodes the metadata file in the given path into a Comic
+func (s *FSSource) read(path string) (Comic, error) {
+	var comic Comic
+
+	bodyText, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return comic, fmt.Errorf("%s: %w", path, ErrComicNotFound)
+		}
+		return comic, err
+	}
+	return parseComic(bodyText)
+}
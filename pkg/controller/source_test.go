@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type SourceUnitSuite struct {
+	suite.Suite
+}
+
+func TestSourceUnitSuite(t *testing.T) {
+	suite.Run(t, &SourceUnitSuite{})
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *SourceUnitSuite) TestHTTPSource() {
+	var userAgent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		switch r.URL.Path {
+		case "/info.0.json":
+			fmt.Fprint(w, `{"num": 2000, "title": "xkcd Phone 2000", "month": "5"}`)
+		case "/42/info.0.json":
+			fmt.Fprint(w, `{"num": 42, "title": "Geico", "month": "1"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	source := NewHTTPSource(&config.Config{
+		UpstreamURL:       upstream.URL + "/",
+		UpstreamTimeout:   1,
+		UpstreamUserAgent: "test-agent",
+	})
+
+	comic, err := source.GetComic(context.Background(), 42)
+	us.Nil(err)
+	us.Equal(Comic{Num: 42, Title: "Geico", Month: "1"}, comic)
+	us.Equal("test-agent", userAgent)
+
+	comic, err = source.GetLatest(context.Background())
+	us.Nil(err)
+	us.Equal(Comic{Num: 2000, Title: "xkcd Phone 2000", Month: "5"}, comic)
+}
+
+func (us *SourceUnitSuite) TestHTTPSourceImage() {
+	var path string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if r.URL.Path != "/comics/geico.jpg" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "image")
+	}))
+	defer upstream.Close()
+
+	// The images are retrieved from the image upstream rather than from the host of their URL
+	source := NewHTTPSource(&config.Config{UpstreamTimeout: 1, ImageUpstreamURL: upstream.URL + "/"})
+	image, err := source.GetImage(context.Background(), Comic{Num: 42, Img: "https://imgs.xkcd.com/comics/geico.jpg"})
+	us.Nil(err)
+	us.Equal("image", string(image))
+	us.Equal("/comics/geico.jpg", path)
+
+	_, err = source.GetImage(context.Background(), Comic{Num: 43, Img: "https://imgs.xkcd.com/comics/missing.jpg"})
+	us.ErrorIs(err, ErrComicNotFound)
+	_, err = source.GetImage(context.Background(), Comic{Num: 44})
+	us.ErrorIs(err, ErrImageNotFound)
+}
+
+func (us *SourceUnitSuite) TestFSSource() {
+	source := NewFSSource("testdata")
+
+	comic, err := source.GetComic(context.Background(), 42)
+	us.Nil(err)
+	us.Equal(42, comic.Num)
+	us.Equal("Geico", comic.Title)
+
+	_, err = source.GetComic(context.Background(), 404)
+	us.ErrorIs(err, ErrComicNotFound)
+
+	// Without a top level metadata file, the comic with the highest number is the latest
+	comic, err = source.GetLatest(context.Background())
+	us.Nil(err)
+	us.Equal(42, comic.Num)
+
+	// The images are stored next to the metadata, under the name of their URL
+	image, err := source.GetImage(context.Background(), comic)
+	us.Nil(err)
+	us.NotEmpty(image)
+	_, err = source.GetImage(context.Background(), Comic{Num: 40, Img: "https://imgs.xkcd.com/comics/light.jpg"})
+	us.ErrorIs(err, ErrImageNotFound)
+}
+
+func (us *SourceUnitSuite) TestFSSourceLatest() {
+	dir := us.T().TempDir()
+
+	_, err := NewFSSource(dir).GetLatest(context.Background())
+	us.ErrorIs(err, ErrComicNotFound)
+
+	us.Nil(os.WriteFile(filepath.Join(dir, metadataFileName), []byte(`{"num": 2800}`), 0o600))
+	comic, err := NewFSSource(dir).GetLatest(context.Background())
+	us.Nil(err)
+	us.Equal(2800, comic.Num)
+}
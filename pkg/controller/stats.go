@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"encoding/xml"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultTopWords is the number of most frequent title words returned when 'top' is not given
+	defaultTopWords = 10
+	// statsCacheSize is the maximum number of statistics kept in the statistics cache
+	statsCacheSize = 256
+)
+
+// statsIgnoredParameters are the query parameters that do not change the statistics, only the way they
+// are rendered
+var statsIgnoredParameters = []string{"format", "columns"}
+
+// comicStats are the aggregate statistics of a set of comics
+type comicStats struct {
+	XMLName xml.Name `json:"-" xml:"stats" yaml:"-"`
+	Count   int      `json:"count" xml:"count,attr" yaml:"count"`
+	// Undated is the number of comics without a valid publication date, which are left out of the
+	// distributions by date
+	Undated     int              `json:"undated" xml:"undated,attr" yaml:"undated"`
+	ByYear      []statsCount     `json:"by_year" xml:"by_year>year" yaml:"by_year"`
+	ByMonth     []statsCount     `json:"by_month" xml:"by_month>month" yaml:"by_month"`
+	MonthParity statsMonthParity `json:"month_parity" xml:"month_parity" yaml:"month_parity"`
+	// AverageTitleLength and AverageAltLength are in characters, rounded to two decimals
+	AverageTitleLength float64       `json:"average_title_length" xml:"average_title_length" yaml:"average_title_length"`
+	AverageAltLength   float64       `json:"average_alt_length" xml:"average_alt_length" yaml:"average_alt_length"`
+	Transcripts        statsCoverage `json:"transcripts" xml:"transcripts" yaml:"transcripts"`
+	TopTitleWords      []statsWord   `json:"top_title_words" xml:"top_title_words>word" yaml:"top_title_words"`
+	Missing            []int         `json:"missing,omitempty" xml:"missing,omitempty" yaml:"missing,omitempty"`
+	Partial            bool          `json:"partial,omitempty" xml:"partial,attr,omitempty" yaml:"partial,omitempty"`
+}
+
+// statsCount is the number of comics published in a given year or month
+type statsCount struct {
+	Value int `json:"value" xml:"value,attr" yaml:"value"`
+	Count int `json:"count" xml:"count,attr" yaml:"count"`
+}
+
+// statsMonthParity is the number of comics published on odd and even months
+type statsMonthParity struct {
+	Odd  int `json:"odd" xml:"odd,attr" yaml:"odd"`
+	Even int `json:"even" xml:"even,attr" yaml:"even"`
+}
+
+// statsCoverage is the number and the ratio of comics having a transcript
+type statsCoverage struct {
+	Count int     `json:"count" xml:"count,attr" yaml:"count"`
+	Ratio float64 `json:"ratio" xml:"ratio,attr" yaml:"ratio"`
+}
+
+// statsWord is a word of the titles along with the number of titles it appears in
+type statsWord struct {
+	Word  string `json:"word" xml:",chardata" yaml:"word"`
+	Count int    `json:"count" xml:"count,attr" yaml:"count"`
+}
+
+// GetStats is a gin handler function that returns aggregate statistics about the comics whose number is
+// in between the 'start' and 'end' query parameters, i.e. the number of comics published per year, per
+// month and on odd and even months, the average length of the titles and alt texts, the ratio of comics
+// having a transcript and the 'top' most frequent words of the titles. The comics are selected like
+// GetComics does, except that the filters keep the comics of any month by default. The statistics are
+// cached for a while, unless upstream is unavailable.
+func (ctrl *Controller) GetStats(c *gin.Context) {
+	start, end, err := getStartEnd(c, ctrl.Cfg.MaxRangeSize)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+	strict, err := getStrict(c)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+	filters, err := parseFilters(c, monthParityAny)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+	top, ok, err := getOptionalInt(c, "top")
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+	if !ok {
+		top = defaultTopWords
+	} else if top < 0 {
+		AbortWithError(c, http.StatusBadRequest, errors.New("please make sure that 'top' is not negative"))
+		return
+	}
+
+	key := statsCacheKey(c)
+	if stats, ok := ctrl.stats.Get(key); ok {
+		renderResponse(c, http.StatusOK, stats)
+		return
+	}
+
+	comics, rf, err := ctrl.fetchMatching(c.Request.Context(), start, end, strict, filters)
+	if err != nil {
+		abortWithFetchError(c, err)
+		return
+	}
+
+	stats := computeStats(comics, top)
+	stats.Missing = rf.Missing()
+	if rf.Partial() {
+		stats.Partial = true
+		c.Header("Warning", degradedWarning)
+	} else {
+		ctrl.stats.Add(key, stats)
+	}
+	renderResponse(c, http.StatusOK, stats)
+}
+
+// computeStats returns the statistics of the given comics, along with the 'top' most frequent words of
+// their titles
+func computeStats(comics []Comic, top int) comicStats {
+	stats := comicStats{Count: len(comics)}
+	years := make(map[int]int)
+	months := make(map[int]int)
+	words := make(map[string]int)
+	var titleLength, altLength int
+
+	for _, comic := range comics {
+		if comic.Published != nil {
+			years[comic.Published.Year()]++
+			months[int(comic.Published.Month())]++
+			if comic.Published.Month()%2 == 1 {
+				stats.MonthParity.Odd++
+			} else {
+				stats.MonthParity.Even++
+			}
+		} else {
+			stats.Undated++
+		}
+
+		titleLength += utf8.RuneCountInString(comic.Title)
+		altLength += utf8.RuneCountInString(comic.Alt)
+		if hasTranscript(comic) {
+			stats.Transcripts.Count++
+		}
+
+		// Words are counted once per title, regardless of their case and accents
+		seen := make(map[string]bool)
+		for _, tok := range tokenize(comic.Title) {
+			word := foldWord(comic.Title[tok.start:tok.end])
+			if !tok.stop && !seen[word] {
+				seen[word] = true
+				words[word]++
+			}
+		}
+	}
+
+	stats.ByYear = sortedCounts(years)
+	stats.ByMonth = sortedCounts(months)
+	if len(comics) > 0 {
+		stats.AverageTitleLength = roundStat(float64(titleLength) / float64(len(comics)))
+		stats.AverageAltLength = roundStat(float64(altLength) / float64(len(comics)))
+		stats.Transcripts.Ratio = roundStat(float64(stats.Transcripts.Count) / float64(len(comics)))
+	}
+
+	stats.TopTitleWords = make([]statsWord, 0, len(words))
+	for word, count := range words {
+		stats.TopTitleWords = append(stats.TopTitleWords, statsWord{Word: word, Count: count})
+	}
+	sort.Slice(stats.TopTitleWords, func(i, j int) bool {
+		a, b := stats.TopTitleWords[i], stats.TopTitleWords[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Word < b.Word
+	})
+	if len(stats.TopTitleWords) > top {
+		stats.TopTitleWords = stats.TopTitleWords[:top]
+	}
+	return stats
+}
+
+// sortedCounts returns the counts by value, in ascending order of the values
+func sortedCounts(counts map[int]int) []statsCount {
+	sorted := make([]statsCount, 0, len(counts))
+	for value, count := range counts {
+		sorted = append(sorted, statsCount{Value: value, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Value < sorted[j].Value })
+	return sorted
+}
+
+// roundStat rounds a statistic to two decimals
+func roundStat(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// statsCacheKey returns the key of the statistics of the request in the statistics cache, i.e. its
+// query parameters in a canonical order, without the ones that only change the rendering
+func statsCacheKey(c *gin.Context) string {
+	query := c.Request.URL.Query()
+	for _, key := range statsIgnoredParameters {
+		query.Del(key)
+	}
+	return query.Encode()
+}
+
+// newStatsCache returns a cache holding at most 'size' statistics by query for 'ttl' each, or nil if the
+// TTL is not positive
+func newStatsCache(size int, ttl time.Duration) *lruCache[string, comicStats] {
+	if ttl <= 0 {
+		return nil
+	}
+	return newLRUCache[string, comicStats](size, ttl, cacheMetrics{})
+}
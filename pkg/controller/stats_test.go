@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type StatsUnitSuite struct {
+	suite.Suite
+}
+
+func TestStatsUnitSuite(t *testing.T) {
+	suite.Run(t, &StatsUnitSuite{})
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *StatsUnitSuite) TestComputeStats() {
+	dates := []Date{NewDate(2006, time.January, 1), NewDate(2006, time.February, 14), NewDate(2007, time.January, 3)}
+	comics := []Comic{
+		{Num: 1, Title: "Barrel - Part 1", Alt: "Don't we all.", Transcript: "[[A boy sits in a barrel.]]", Published: &dates[0]},
+		{Num: 2, Title: "Petit Trees (sketch)", Alt: "'Petit' being a reference to Le Petit Prince", Published: &dates[1]},
+		{Num: 3, Title: "Island (sketch)", Alt: "Hello, island", Transcript: " \n", Published: &dates[2]},
+		{Num: 4, Title: "The Barrel", Alt: ""},
+	}
+
+	testCases := []struct {
+		name           string
+		comics         []Comic
+		top            int
+		expectedOutput comicStats
+	}{
+		{
+			"No Comics",
+			nil,
+			10,
+			comicStats{ByYear: []statsCount{}, ByMonth: []statsCount{}, TopTitleWords: []statsWord{}},
+		},
+		{
+			"Comics",
+			comics,
+			3,
+			comicStats{
+				Count:              4,
+				Undated:            1,
+				ByYear:             []statsCount{{Value: 2006, Count: 2}, {Value: 2007, Count: 1}},
+				ByMonth:            []statsCount{{Value: 1, Count: 2}, {Value: 2, Count: 1}},
+				MonthParity:        statsMonthParity{Odd: 2, Even: 1},
+				AverageTitleLength: 15,
+				AverageAltLength:   17.5,
+				Transcripts:        statsCoverage{Count: 1, Ratio: 0.25},
+				TopTitleWords:      []statsWord{{Word: "barrel", Count: 2}, {Word: "sketch", Count: 2}, {Word: "1", Count: 1}},
+			},
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			us.Equal(test.expectedOutput, computeStats(test.comics, test.top))
+		})
+	}
+}
+
+func (us *StatsUnitSuite) TestStatsCache() {
+	now := time.Date(2006, time.January, 1, 0, 0, 0, 0, time.UTC)
+	cache := newStatsCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Add("a", comicStats{Count: 1})
+	cache.Add("b", comicStats{Count: 2})
+	stats, ok := cache.Get("a")
+	us.True(ok)
+	us.Equal(1, stats.Count)
+
+	// The least recently used statistics are evicted first
+	cache.Add("c", comicStats{Count: 3})
+	us.Equal(2, cache.Len())
+	_, ok = cache.Get("b")
+	us.False(ok)
+
+	// The statistics expire after the TTL
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.Get("a")
+	us.False(ok)
+
+	cache.Purge()
+	us.Equal(0, cache.Len())
+
+	// A non-positive TTL disables the cache
+	disabled := newStatsCache(2, 0)
+	us.Nil(disabled)
+	disabled.Add("a", comicStats{Count: 1})
+	_, ok = disabled.Get("a")
+	us.False(ok)
+	us.Equal(0, disabled.Len())
+}
+
+func (us *StatsUnitSuite) TestStatsEndpoint() {
+	conf := config.NewConfig()
+	conf.FetchWorkers = 2
+	conf.StatsCacheTTL = 60
+	controller := NewController(conf, NewFSSource("testdata"), nil)
+
+	router := gin.New()
+	router.GET("/stats", controller.GetStats)
+
+	testCases := []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			"Stats",
+			"/stats?start=40&end=43&top=2",
+			200,
+			`{"count":3,"undated":0,"by_year":[{"value":2006,"count":3}],"by_month":[{"value":1,"count":3}],"month_parity":{"odd":3,"even":0},"average_title_length":7,"average_alt_length":18.67,"transcripts":{"count":3,"ratio":1},"top_title_words":[{"word":"drawing","count":1},{"word":"geico","count":1}],"missing":[43]}`,
+		},
+		{
+			"Filtered Stats",
+			"/stats?start=40&end=42&month_parity=even",
+			200,
+			`{"count":0,"undated":0,"by_year":[],"by_month":[],"month_parity":{"odd":0,"even":0},"average_title_length":0,"average_alt_length":0,"transcripts":{"count":0,"ratio":0},"top_title_words":[]}`,
+		},
+		{
+			"Negative Top",
+			"/stats?start=40&end=42&top=-1",
+			400,
+			`{"error":"please make sure that 'top' is not negative"}`,
+		},
+		{
+			"Missing Range",
+			"/stats",
+			400,
+			`{"error":"please include the starting and ending comic numbers"}`,
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, test.path, nil)
+			us.Nil(err)
+			router.ServeHTTP(recorder, request)
+			us.Equal(test.expectedStatus, recorder.Code)
+			us.Equal(test.expectedResponse, recorder.Body.String())
+		})
+	}
+
+	// The statistics are cached regardless of the format they are rendered in
+	us.Equal(2, controller.stats.Len())
+	_, ok := controller.stats.Get("end=43&start=40&top=2")
+	us.True(ok)
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/stats?start=40&end=43&top=2&format=yaml", nil)
+	us.Nil(err)
+	router.ServeHTTP(recorder, request)
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal(2, controller.stats.Len())
+}
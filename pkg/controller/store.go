@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	storeFileName = "comics.db"
+	storeTimeout  = time.Second
+)
+
+var (
+	// comicsBucket is the bbolt bucket holding the comics, keyed by their big-endian encoded number
+	comicsBucket = []byte("comics")
+	// gapsBucket is the bbolt bucket holding the numbers that were never published, such as #404, keyed
+	// like the comics
+	gapsBucket = []byte("gaps")
+)
+
+// ComicStore is a persistent, on-disk store of comic metadata. A nil *ComicStore is a valid,
+// always empty store.
+type ComicStore struct {
+	db *bolt.DB
+}
+
+// OpenComicStore opens the store located in the given directory, creating it if it does not exist
+func OpenComicStore(dir string) (*ComicStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(filepath.Join(dir, storeFileName), 0o600, &bolt.Options{Timeout: storeTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{comicsBucket, gapsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &ComicStore{db: db}, nil
+}
+
+// Close releases the underlying database file
+func (s *ComicStore) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Get returns the comic with the given number and whether it was found in the store
+func (s *ComicStore) Get(num int) (Comic, bool, error) {
+	var (
+		comic Comic
+		found bool
+	)
+	if s == nil {
+		return comic, false, nil
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(comicsBucket).Get(storeKey(num))
+		if value == nil {
+			return nil
+		}
+		found = true
+		// Comics stored before their publication date was parsed at ingestion get it now
+		var err error
+		comic, err = parseComic(value)
+		return err
+	})
+	return comic, found, err
+}
+
+// Put inserts the given comics into the store, replacing the ones with the same number
+func (s *ComicStore) Put(comics ...Comic) error {
+	if s == nil || len(comics) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(comicsBucket)
+		for i := range comics {
+			value, err := json.Marshal(comics[i])
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(storeKey(comics[i].Num), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// All returns every comic of the store, in ascending order of their number
+func (s *ComicStore) All() ([]Comic, error) {
+	var comics []Comic
+	if s == nil {
+		return nil, nil
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(comicsBucket).ForEach(func(_, value []byte) error {
+			comic, err := parseComic(value)
+			if err != nil {
+				return err
+			}
+			comics = append(comics, comic)
+			return nil
+		})
+	})
+	return comics, err
+}
+
+// Count returns the number of comics in the store
+func (s *ComicStore) Count() (int, error) {
+	var count int
+	if s == nil {
+		return 0, nil
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(comicsBucket).Stats().KeyN
+		return nil
+	})
+	return count, err
+}
+
+// MarkGaps records that the comics with the given numbers do not exist upstream, so that they are no
+// longer reported as missing
+func (s *ComicStore) MarkGaps(nums ...int) error {
+	if s == nil || len(nums) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(gapsBucket)
+		for _, num := range nums {
+			if err := bucket.Put(storeKey(num), []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Missing returns the numbers in between 1 and latest (inclusive) that are neither in the store nor
+// known gaps, in ascending order
+func (s *ComicStore) Missing(latest int) ([]int, error) {
+	var missing []int
+	if s == nil {
+		for num := 1; num <= latest; num++ {
+			missing = append(missing, num)
+		}
+		return missing, nil
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		gaps := tx.Bucket(gapsBucket)
+		add := func(num int) {
+			if gaps.Get(storeKey(num)) == nil {
+				missing = append(missing, num)
+			}
+		}
+		// The keys are iterated in ascending order, so the gaps in between them are the missing numbers
+		next := 1
+		cursor := tx.Bucket(comicsBucket).Cursor()
+		for key, _ := cursor.First(); key != nil && next <= latest; key, _ = cursor.Next() {
+			num := int(binary.BigEndian.Uint64(key))
+			for ; next < num && next <= latest; next++ {
+				add(next)
+			}
+			next = num + 1
+		}
+		for ; next <= latest; next++ {
+			add(next)
+		}
+		return nil
+	})
+	return missing, err
+}
+
+// storeKey encodes a comic number so that the keys of the bucket are sorted numerically
+func storeKey(num int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(num))
+	return key
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type StoreUnitSuite struct {
+	suite.Suite
+	store *ComicStore
+}
+
+func TestStoreUnitSuite(t *testing.T) {
+	suite.Run(t, &StoreUnitSuite{})
+}
+
+func (us *StoreUnitSuite) SetupTest() {
+	store, err := OpenComicStore(us.T().TempDir())
+	us.Require().Nil(err)
+	us.store = store
+}
+
+func (us *StoreUnitSuite) TearDownTest() {
+	us.Nil(us.store.Close())
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *StoreUnitSuite) TestPutGet() {
+	_, found, err := us.store.Get(42)
+	us.Nil(err)
+	us.False(found)
+
+	us.Nil(us.store.Put(Comic{Num: 42, Title: "Geico"}, Comic{Num: 40, Title: "Light"}))
+	comic, found, err := us.store.Get(42)
+	us.Nil(err)
+	us.True(found)
+	us.Equal(Comic{Num: 42, Title: "Geico"}, comic)
+
+	count, err := us.store.Count()
+	us.Nil(err)
+	us.Equal(2, count)
+
+	all, err := us.store.All()
+	us.Nil(err)
+	us.Equal([]Comic{{Num: 40, Title: "Light"}, {Num: 42, Title: "Geico"}}, all)
+}
+
+func (us *StoreUnitSuite) TestMissing() {
+	us.Nil(us.store.Put(Comic{Num: 2}, Comic{Num: 3}, Comic{Num: 6}, Comic{Num: 300}))
+
+	testCases := []struct {
+		name           string
+		latest         int
+		expectedOutput []int
+	}{
+		{"Gaps In Between", 8, []int{1, 4, 5, 7, 8}},
+		{"Stored Comics Past The Latest", 4, []int{1, 4}},
+		{"Nothing Published", 0, nil},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			missing, err := us.store.Missing(test.latest)
+			us.Nil(err)
+			us.Equal(test.expectedOutput, missing)
+		})
+	}
+}
+
+func (us *StoreUnitSuite) TestMarkGaps() {
+	us.Nil(us.store.Put(Comic{Num: 2}))
+	us.Nil(us.store.MarkGaps(1, 4))
+
+	missing, err := us.store.Missing(5)
+	us.Nil(err)
+	us.Equal([]int{3, 5}, missing)
+	all, err := us.store.All()
+	us.Nil(err)
+	us.Len(all, 1)
+}
+
+func (us *StoreUnitSuite) TestNilStore() {
+	var store *ComicStore
+
+	_, found, err := store.Get(1)
+	us.Nil(err)
+	us.False(found)
+	us.Nil(store.Put(Comic{Num: 1}))
+	missing, err := store.Missing(3)
+	us.Nil(err)
+	us.Equal([]int{1, 2, 3}, missing)
+	us.Nil(store.MarkGaps(1))
+	all, err := store.All()
+	us.Nil(err)
+	us.Nil(all)
+	us.Nil(store.Close())
+}
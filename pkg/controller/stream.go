@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/httputil"
+)
+
+// mimeNDJSON is the media type of newline delimited JSON
+const mimeNDJSON = "application/x-ndjson"
+
+// streamQuery describes the comics of a streamed range
+type streamQuery struct {
+	start, end int
+	strict     bool
+	filters    []comicFilter
+	compare    comicComparator
+	// window is the number of comics that are buffered and sorted before being emitted, the comics are
+	// emitted as soon as they are fetched when it is zero
+	window int
+	// expand is set when the comics come with their structured transcript
+	expand bool
+}
+
+// streamSummary is the last event of a Server-Sent Events stream, and the last line of a newline
+// delimited JSON one
+type streamSummary struct {
+	Count   int   `json:"count"`
+	Missing []int `json:"missing,omitempty"`
+	Partial bool  `json:"partial,omitempty"`
+}
+
+// parseStreamQuery extracts the range, the filters and the sort order of a stream from the query
+// parameters, just like GetComics does, along with the optional 'window' and 'expand' query parameters
+func parseStreamQuery(c *gin.Context, maxRange int) (streamQuery, error) {
+	var (
+		q   streamQuery
+		err error
+	)
+	if q.start, q.end, err = getStartEnd(c, maxRange); err != nil {
+		return q, err
+	}
+	if q.strict, err = getStrict(c); err != nil {
+		return q, err
+	}
+	if q.filters, err = parseFilters(c, monthParityOdd); err != nil {
+		return q, err
+	}
+	if q.compare, err = parseSort(c); err != nil {
+		return q, err
+	}
+	window, _, err := getOptionalInt(c, "window")
+	if err != nil {
+		return q, err
+	}
+	if window < 0 {
+		return q, errors.New("please make sure that 'window' is not negative")
+	}
+	q.window = window
+	if q.expand, err = getExpandTranscript(c); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// streamRange fetches the comics of the query and emits the ones matching its filters, either as soon
+// as they are fetched, or sorted in windows of the given size, expanded if the query asks for it
+func (ctrl *Controller) streamRange(ctx context.Context, q streamQuery, rf *rangeFetch, emit func(Comic) error) error {
+	if q.expand {
+		emitComic := emit
+		emit = func(comic Comic) error {
+			return emitComic(expandTranscript(comic))
+		}
+	}
+
+	var buffer []Comic
+	flush := func() error {
+		sortComics(buffer, q.compare)
+		for _, comic := range buffer {
+			if err := emit(comic); err != nil {
+				return err
+			}
+		}
+		buffer = buffer[:0]
+		return nil
+	}
+
+	err := streamComics(ctx, q.start, q.end, ctrl.Cfg.FetchWorkers, rf.Fetch, func(_ int, comic Comic) error {
+		if !matchesFilters(comic, q.filters) {
+			return nil
+		}
+		if q.window == 0 {
+			return emit(comic)
+		}
+		if buffer = append(buffer, comic); len(buffer) < q.window {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// acceptsNDJSON returns whether the comics should be streamed as newline delimited JSON, i.e. whether
+// the 'format' query parameter is 'ndjson', or the 'Accept' header prefers it over the other formats
+func acceptsNDJSON(c *gin.Context) bool {
+	if name := c.Query("format"); name != "" {
+		return name == "ndjson"
+	}
+	offered := []string{gin.MIMEJSON, mimeNDJSON}
+	for _, format := range responseFormats[1:] {
+		offered = append(offered, format.mediaTypes...)
+	}
+	return negotiateMediaType(c.GetHeader("Accept"), offered...) == mimeNDJSON
+}
+
+// streamNDJSON responds to GetComics with the comics as newline delimited JSON, flushing every comic as
+// soon as it is emitted by streamRange. The stream ends with either a 'summary' object, which tells the
+// comics that were skipped, or an 'error' object. Errors that occur before the first comic fail the
+// request as usual.
+func (ctrl *Controller) streamNDJSON(c *gin.Context) {
+	// The same URL is served as JSON to the other clients
+	httputil.AddVary(c.Writer.Header(), "Accept")
+	q, err := parseStreamQuery(c, ctrl.Cfg.MaxRangeSize)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var count int
+	rf := &rangeFetch{ctrl: ctrl, strict: q.strict}
+	encoder := json.NewEncoder(c.Writer)
+	err = ctrl.streamRange(c.Request.Context(), q, rf, func(comic Comic) error {
+		if count == 0 {
+			c.Header("Content-Type", mimeNDJSON)
+			c.Status(http.StatusOK)
+		}
+		if err := encoder.Encode(ctrl.proxyImage(c, comic)); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		count++
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		if count == 0 {
+			c.Header("Content-Type", mimeNDJSON)
+			c.Status(http.StatusOK)
+		}
+		_ = encoder.Encode(gin.H{"summary": streamSummary{Count: count, Missing: rf.Missing(), Partial: rf.Partial()}})
+	case count == 0:
+		abortWithFetchError(c, err)
+	case c.Request.Context().Err() == nil:
+		_ = c.Error(err)
+		_ = encoder.Encode(gin.H{"error": err.Error()})
+	default:
+		// The client is gone, there is nobody left to tell
+		_ = c.Error(err)
+	}
+}
+
+// GetComicsStream is a gin handler function that streams the comics selected like GetComics does as
+// Server-Sent Events. Every comic is sent in a 'comic' event as soon as it is emitted by streamRange,
+// and the stream ends with either an 'end' event summarizing it, or an 'error' event. Errors that occur
+// before the first comic fail the request as usual.
+func (ctrl *Controller) GetComicsStream(c *gin.Context) {
+	q, err := parseStreamQuery(c, ctrl.Cfg.MaxRangeSize)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Prevent the proxies from caching or buffering the events
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	var count int
+	rf := &rangeFetch{ctrl: ctrl, strict: q.strict}
+	err = ctrl.streamRange(c.Request.Context(), q, rf, func(comic Comic) error {
+		c.SSEvent("comic", ctrl.proxyImage(c, comic))
+		c.Writer.Flush()
+		count++
+		return nil
+	})
+
+	switch {
+	case err != nil && count == 0:
+		abortWithFetchError(c, err)
+		return
+	case err != nil:
+		_ = c.Error(err)
+		if c.Request.Context().Err() == nil {
+			c.SSEvent("error", gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.SSEvent("end", streamSummary{Count: count, Missing: rf.Missing(), Partial: rf.Partial()})
+	c.Writer.Flush()
+}
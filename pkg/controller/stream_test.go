@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type StreamUnitSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func TestStreamUnitSuite(t *testing.T) {
+	suite.Run(t, &StreamUnitSuite{})
+}
+
+func (us *StreamUnitSuite) SetupSuite() {
+	conf := config.NewConfig()
+	conf.FetchWorkers = 2
+	controller := NewController(conf, NewFSSource("testdata"), nil)
+
+	us.router = gin.New()
+	us.router.GET("/comics", controller.GetComics)
+	us.router.GET("/comics/stream", controller.GetComicsStream)
+}
+
+// serve returns the recorded response to a GET request
+func (us *StreamUnitSuite) serve(path, accept string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, path, nil)
+	us.Require().Nil(err)
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	us.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *StreamUnitSuite) TestNDJSON() {
+	testCases := []struct {
+		name            string
+		query           string
+		expectedStatus  int
+		expectedNums    []int
+		expectedSummary string
+		expectedError   string
+	}{
+		{"Unsorted", "?start=40&end=44", 200, nil, `{"count":3,"missing":[43,44]}`, ""},
+		{"Sorted In A Single Window", "?start=40&end=44&window=10", 200, []int{42, 40, 41}, `{"count":3,"missing":[43,44]}`, ""},
+		{"Filtered Out", "?start=40&end=42&month_parity=even", 200, []int{}, `{"count":0}`, ""},
+		{"Strict", "?start=40&end=44&strict=true&window=10", 404, nil, "", "comic not found"},
+		{"Invalid Window", "?start=40&end=42&window=-1", 400, nil, "", "please make sure that 'window' is not negative"},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := us.serve("/comics"+test.query, mimeNDJSON)
+			us.Equal(test.expectedStatus, recorder.Code)
+			if test.expectedError != "" {
+				us.Contains(recorder.Body.String(), test.expectedError)
+				return
+			}
+			us.Equal(mimeNDJSON, recorder.Header().Get("Content-Type"))
+			us.Equal("Accept", recorder.Header().Get("Vary"))
+
+			// The comics are followed by the summary of the stream
+			var lines [][]byte
+			scanner := bufio.NewScanner(recorder.Body)
+			for scanner.Scan() {
+				lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+			}
+			us.Require().NotEmpty(lines)
+			us.JSONEq(`{"summary":`+test.expectedSummary+`}`, string(lines[len(lines)-1]))
+			nums := []int{}
+			for _, line := range lines[:len(lines)-1] {
+				var comic Comic
+				us.Nil(json.Unmarshal(line, &comic))
+				nums = append(nums, comic.Num)
+			}
+			if test.expectedNums == nil {
+				us.ElementsMatch([]int{40, 41, 42}, nums)
+			} else {
+				us.Equal(test.expectedNums, nums)
+			}
+		})
+	}
+
+	// JSON stays the default
+	recorder := us.serve("/comics?start=40&end=42", "*/*")
+	us.Equal(http.StatusOK, recorder.Code)
+	us.True(strings.HasPrefix(recorder.Header().Get("Content-Type"), gin.MIMEJSON))
+}
+
+func (us *StreamUnitSuite) TestServerSentEvents() {
+	recorder := us.serve("/comics/stream?start=40&end=44&window=2", "")
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal("text/event-stream", recorder.Header().Get("Content-Type"))
+	us.Equal("no-cache", recorder.Header().Get("Cache-Control"))
+
+	var events []string
+	var data []string
+	for _, line := range strings.Split(recorder.Body.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			events = append(events, line[len("event:"):])
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, line[len("data:"):])
+		}
+	}
+	us.Equal([]string{"comic", "comic", "comic", "end"}, events)
+	us.JSONEq(`{"count":3,"missing":[43,44]}`, data[len(data)-1])
+
+	// Failures before the first comic fail the request as usual
+	recorder = us.serve("/comics/stream?start=43&end=44&strict=true", "")
+	us.Equal(http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Syncer periodically discovers the latest comic published upstream and backfills the store with
+// every comic that it is missing, which are also added to the search index
+type Syncer struct {
+	Store    *ComicStore
+	Source   ComicSource
+	Index    *SearchIndex
+	Interval time.Duration
+	Logger   *logrus.Logger
+}
+
+// NewSyncer returns a pointer to a new Syncer instance that copies the comics of the source into the
+// store and the search index. The index may be nil.
+func NewSyncer(store *ComicStore, source ComicSource, index *SearchIndex, interval time.Duration, logger *logrus.Logger) *Syncer {
+	return &Syncer{
+		Store:    store,
+		Source:   source,
+		Index:    index,
+		Interval: interval,
+		Logger:   logger,
+	}
+}
+
+// Run synchronizes the store right away and then once every interval, until the context is cancelled.
+// The synchronization is disabled when the interval is not positive.
+func (s *Syncer) Run(ctx context.Context) {
+	if s.Interval <= 0 {
+		s.Logger.Info("comic store synchronization disabled")
+		return
+	}
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		added, err := s.Sync(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.Logger.WithError(err).Error("comic store synchronization failed")
+		} else if added > 0 {
+			s.Logger.WithField("added", added).Info("comic store synchronized")
+		}
+
+		select {
+		case <-ctx.Done():
+			s.Logger.Info("comic store synchronization stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync fetches the latest comic and every comic that is missing from the store, and returns the
+// number of comics that were added. Comics that can not be fetched are skipped until the next sync,
+// except the ones that do not exist upstream, such as #404, which are recorded as gaps in the store.
+// The sync is interrupted as soon as the circuit breaker opens, since every other fetch would fail.
+func (s *Syncer) Sync(ctx context.Context) (int, error) {
+	latest, err := s.Source.GetLatest(ctx)
+	if err != nil {
+		return 0, err
+	}
+	missing, err := s.Store.Missing(latest.Num)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	var gaps []int
+	// The gaps found so far are recorded however the sync ends
+	defer func() {
+		if err := s.Store.MarkGaps(gaps...); err != nil {
+			s.Logger.WithError(err).Warn("could not record the comics that do not exist")
+		}
+	}()
+	for i, num := range missing {
+		if err := ctx.Err(); err != nil {
+			return added, err
+		}
+
+		comic := latest
+		if num != latest.Num {
+			comic, err = s.Source.GetComic(ctx, num)
+			if errors.Is(err, ErrComicNotFound) {
+				gaps = append(gaps, num)
+				continue
+			}
+			if errors.Is(err, ErrCircuitOpen) {
+				return added, fmt.Errorf("interrupted with %d comics left: %w", len(missing)-i, err)
+			}
+			if err != nil {
+				s.Logger.WithError(err).WithField("num", num).Warn("could not fetch comic")
+				continue
+			}
+		}
+		if err := s.Store.Put(comic); err != nil {
+			return added, err
+		}
+		s.Index.Add(comic)
+		added++
+	}
+	return added, nil
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type SyncUnitSuite struct {
+	suite.Suite
+	store  *ComicStore
+	logger *logrus.Logger
+}
+
+func TestSyncUnitSuite(t *testing.T) {
+	suite.Run(t, &SyncUnitSuite{})
+}
+
+func (us *SyncUnitSuite) SetupTest() {
+	store, err := OpenComicStore(us.T().TempDir())
+	us.Require().Nil(err)
+	us.store = store
+	us.logger = logrus.New()
+	us.logger.SetOutput(io.Discard)
+}
+
+func (us *SyncUnitSuite) TearDownTest() {
+	us.Nil(us.store.Close())
+}
+
+// openCircuitSource is a ComicSource whose circuit breaker is open, except for the latest comic
+type openCircuitSource struct {
+	ComicSource
+	calls int
+}
+
+func (s *openCircuitSource) GetComic(ctx context.Context, num int) (Comic, error) {
+	s.calls++
+	return Comic{}, ErrCircuitOpen
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *SyncUnitSuite) TestSync() {
+	index := NewSearchIndex()
+	syncer := NewSyncer(us.store, NewFSSource("testdata"), index, time.Hour, us.logger)
+
+	// Only comics 40 to 42 exist in the test data, the rest are skipped
+	added, err := syncer.Sync(context.Background())
+	us.Nil(err)
+	us.Equal(3, added)
+	us.Equal(3, index.Len())
+
+	comic, found, err := us.store.Get(41)
+	us.Nil(err)
+	us.True(found)
+	us.Equal("Old Drawing", comic.Title)
+
+	// The comics that do not exist are not fetched again
+	missing, err := us.store.Missing(42)
+	us.Nil(err)
+	us.Empty(missing)
+	added, err = syncer.Sync(context.Background())
+	us.Nil(err)
+	us.Equal(0, added)
+}
+
+func (us *SyncUnitSuite) TestSyncInterruptedByOpenCircuit() {
+	source := &openCircuitSource{ComicSource: NewFSSource("testdata")}
+	hook := test.NewLocal(us.logger)
+	syncer := NewSyncer(us.store, source, nil, time.Hour, us.logger)
+
+	// The first failure ends the sync, without a warning per comic
+	added, err := syncer.Sync(context.Background())
+	us.ErrorIs(err, ErrCircuitOpen)
+	us.Equal("interrupted with 42 comics left: "+ErrCircuitOpen.Error(), err.Error())
+	us.Equal(0, added)
+	us.Equal(1, source.calls)
+	us.Empty(hook.AllEntries())
+
+	// Nothing was recorded as a gap
+	missing, err := us.store.Missing(42)
+	us.Nil(err)
+	us.Len(missing, 42)
+}
+
+func (us *SyncUnitSuite) TestRunStopsOnCancel() {
+	syncer := NewSyncer(us.store, NewFSSource("testdata"), nil, time.Millisecond, us.logger)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		syncer.Run(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		us.Fail("the syncer did not stop after the context was cancelled")
+	}
+}
+
+func (us *SyncUnitSuite) TestRunDisabled() {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		syncer := NewSyncer(us.store, NewFSSource("testdata"), nil, interval, us.logger)
+
+		done := make(chan struct{})
+		go func() {
+			syncer.Run(context.Background())
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			us.Fail("the syncer ran although its interval is not positive")
+		}
+
+		_, found, err := us.store.Get(42)
+		us.Nil(err)
+		us.False(found)
+	}
+}
+
+func (us *SyncUnitSuite) TestControllerServesFromStore() {
+	us.Nil(us.store.Put(Comic{Num: 1, Title: "Barrel - Part 1", Month: "1"}))
+
+	// The test data does not hold comic 1, so it can only come from the store
+	ctrl := NewController(config.NewConfig(), NewFSSource("testdata"), us.store)
+	comic, err := ctrl.getComic(context.Background(), 1)
+	us.Nil(err)
+	us.Equal("Barrel - Part 1", comic.Title)
+
+	// Comics that are not stored yet are fetched from the source and persisted
+	_, err = ctrl.getComic(context.Background(), 40)
+	us.Nil(err)
+	_, found, err := us.store.Get(40)
+	us.Nil(err)
+	us.True(found)
+}
@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The kinds of the elements of a transcript
+const (
+	// elementScene is a description of the scene, written as [[...]]
+	elementScene = "scene"
+	// elementLine is a line said by a speaker, written as 'Speaker: line'
+	elementLine = "line"
+	// elementText is any other text, such as captions and labels
+	elementText = "text"
+)
+
+var (
+	// transcriptMarkup matches the scene descriptions and the title text of a transcript, which may
+	// span several lines
+	transcriptMarkup = regexp.MustCompile(`(?s)\[\[(.*?)\]\]|\{\{(.*?)\}\}`)
+	// titleTextPrefix matches the label that the title text of a transcript usually starts with
+	titleTextPrefix = regexp.MustCompile(`(?i)^(alt|alt[- ]?text|title[- ]?text)\s*:\s*`)
+	// speakerPattern matches the lines of dialogue, the speaker being a few words without punctuation
+	speakerPattern = regexp.MustCompile(`^([^\s:.,!?"()\[\]{}][^:.,!?"()\[\]{}]{0,39}?)\s*:(?:\s+(.*))?$`)
+)
+
+// maxSpeakerWords is the maximum number of words in the name of a speaker, so that sentences containing
+// a colon are not mistaken for dialogue
+const maxSpeakerWords = 4
+
+// Transcript is the structured representation of the transcript of a comic
+type Transcript struct {
+	Panels []TranscriptPanel `json:"panels" xml:"panel" yaml:"panels"`
+	// Speakers are the distinct speakers of the transcript, in order of appearance
+	Speakers  []string `json:"speakers" xml:"speaker" yaml:"speakers"`
+	TitleText string   `json:"title_text,omitempty" xml:"title_text,omitempty" yaml:"title_text,omitempty"`
+}
+
+// TranscriptPanel is a panel of a comic, as described by its transcript
+type TranscriptPanel struct {
+	Elements []TranscriptElement `json:"elements" xml:"element" yaml:"elements"`
+}
+
+// TranscriptElement is a scene description, a line of dialogue or a piece of text of a panel
+type TranscriptElement struct {
+	Kind    string `json:"kind" xml:"kind,attr" yaml:"kind"`
+	Speaker string `json:"speaker,omitempty" xml:"speaker,attr,omitempty" yaml:"speaker,omitempty"`
+	Text    string `json:"text" xml:",chardata" yaml:"text"`
+}
+
+// ParseTranscript parses the raw transcript of a comic, in which the scene descriptions are written as
+// [[...]], the title text as {{...}} and the dialogue as 'Speaker: line'. Panels are separated by blank
+// lines, and a scene description that follows other elements also starts a new panel.
+func ParseTranscript(raw string) Transcript {
+	p := transcriptParser{transcript: Transcript{Panels: []TranscriptPanel{}, Speakers: []string{}}}
+	offset := 0
+	for _, match := range transcriptMarkup.FindAllStringSubmatchIndex(raw, -1) {
+		p.text(raw[offset:match[0]])
+		if match[2] >= 0 {
+			p.scene(raw[match[2]:match[3]])
+		} else {
+			p.titleText(raw[match[4]:match[5]])
+		}
+		offset = match[1]
+	}
+	p.text(raw[offset:])
+	p.endPanel()
+	return p.transcript
+}
+
+// transcriptParser accumulates the elements of a transcript as it is parsed
+type transcriptParser struct {
+	transcript Transcript
+	panel      []TranscriptElement
+	// speaker is the speaker of a line whose text has not been found yet, e.g. in 'Cueball: [[...]] Hi'
+	speaker string
+}
+
+// endPanel adds the current panel to the transcript, if it is not empty
+func (p *transcriptParser) endPanel() {
+	if len(p.panel) > 0 {
+		p.transcript.Panels = append(p.transcript.Panels, TranscriptPanel{Elements: p.panel})
+		p.panel = nil
+	}
+}
+
+// add adds an element to the current panel
+func (p *transcriptParser) add(element TranscriptElement) {
+	if element.Speaker != "" && !contains(p.transcript.Speakers, element.Speaker) {
+		p.transcript.Speakers = append(p.transcript.Speakers, element.Speaker)
+	}
+	p.panel = append(p.panel, element)
+}
+
+// scene adds a scene description, starting a new panel unless the current one only has descriptions or
+// the description is part of a line of dialogue
+func (p *transcriptParser) scene(description string) {
+	description = collapseSpaces(description)
+	if description == "" {
+		return
+	}
+	if p.speaker == "" {
+		for _, element := range p.panel {
+			if element.Kind != elementScene {
+				p.endPanel()
+				break
+			}
+		}
+	}
+	p.add(TranscriptElement{Kind: elementScene, Text: description})
+}
+
+// titleText sets the title text of the transcript, without the label it may start with
+func (p *transcriptParser) titleText(text string) {
+	text = collapseSpaces(titleTextPrefix.ReplaceAllString(strings.TrimSpace(text), ""))
+	if text == "" {
+		return
+	}
+	if p.transcript.TitleText != "" {
+		text = p.transcript.TitleText + " " + text
+	}
+	p.transcript.TitleText = text
+}
+
+// text adds the lines of dialogue and the pieces of text found in between the markup
+func (p *transcriptParser) text(chunk string) {
+	lines := strings.Split(chunk, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			// The first and last lines of a chunk are the ends of the lines that hold the markup
+			if i > 0 && i < len(lines)-1 {
+				p.speaker = ""
+				p.endPanel()
+			}
+			continue
+		}
+
+		if speaker, text, ok := parseDialogue(line); ok {
+			if text == "" {
+				p.speaker = speaker
+				continue
+			}
+			p.speaker = ""
+			p.add(TranscriptElement{Kind: elementLine, Speaker: speaker, Text: text})
+			continue
+		}
+		if p.speaker != "" {
+			p.add(TranscriptElement{Kind: elementLine, Speaker: p.speaker, Text: collapseSpaces(line)})
+			p.speaker = ""
+			continue
+		}
+		p.add(TranscriptElement{Kind: elementText, Text: collapseSpaces(line)})
+	}
+}
+
+// parseDialogue splits a line of dialogue into its speaker and its text, which is empty when it is on
+// the next line or follows a scene description
+func parseDialogue(line string) (string, string, bool) {
+	match := speakerPattern.FindStringSubmatch(line)
+	if match == nil || len(strings.Fields(match[1])) > maxSpeakerWords {
+		return "", "", false
+	}
+	return collapseSpaces(match[1]), collapseSpaces(match[2]), true
+}
+
+// collapseSpaces trims the text and replaces the runs of whitespace it contains with a single space
+func collapseSpaces(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// transcriptResponse is the body of a successful response containing the transcript of a comic
+type transcriptResponse struct {
+	XMLName    xml.Name `json:"-" xml:"transcript" yaml:"-"`
+	Num        int      `json:"num" xml:"num,attr" yaml:"num"`
+	Transcript `yaml:",inline"`
+}
+
+// GetComicTranscript is a gin handler function that returns the structured transcript of the comic
+// whose number is given in the path, see ParseTranscript. Comics without a transcript have no panels.
+func (ctrl *Controller) GetComicTranscript(c *gin.Context) {
+	num, err := strconv.Atoi(c.Param("num"))
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, errors.New("please make sure that the comic number is an integer"))
+		return
+	}
+
+	comic, err := ctrl.getComic(c.Request.Context(), num)
+	if err != nil {
+		abortWithFetchError(c, err)
+		return
+	}
+	renderResponse(c, http.StatusOK, transcriptResponse{Num: comic.Num, Transcript: ParseTranscript(comic.Transcript)})
+}
+
+// getExpandTranscript returns whether the 'expand' query parameter, a comma separated list of the
+// representations to add to the comics, asks for their structured transcript
+func getExpandTranscript(c *gin.Context) (bool, error) {
+	value := c.Query("expand")
+	if value == "" {
+		return false, nil
+	}
+	for _, name := range strings.Split(value, ",") {
+		if strings.TrimSpace(name) != "transcript" {
+			return false, errors.New("please make sure that 'expand' only contains 'transcript'")
+		}
+	}
+	return true, nil
+}
+
+// expandTranscript returns the comic along with its structured transcript
+func expandTranscript(comic Comic) Comic {
+	transcript := ParseTranscript(comic.Transcript)
+	comic.ParsedTranscript = &transcript
+	return comic
+}
+
+// transcriptRows returns the CSV rows of a transcript, one per element
+func transcriptRows(response transcriptResponse) [][]string {
+	rows := [][]string{{"num", "panel", "kind", "speaker", "text"}}
+	for i, panel := range response.Panels {
+		for _, element := range panel.Elements {
+			rows = append(rows, []string{strconv.Itoa(response.Num), strconv.Itoa(i + 1), element.Kind, element.Speaker, element.Text})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,221 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type TranscriptUnitSuite struct {
+	suite.Suite
+}
+
+func TestTranscriptUnitSuite(t *testing.T) {
+	suite.Run(t, &TranscriptUnitSuite{})
+}
+
+// panel returns a transcript panel made of the given elements
+func panel(elements ...TranscriptElement) TranscriptPanel {
+	return TranscriptPanel{Elements: elements}
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *TranscriptUnitSuite) TestParseTranscript() {
+	testCases := []struct {
+		name           string
+		input          string
+		expectedOutput Transcript
+	}{
+		{
+			"Empty",
+			"",
+			Transcript{Panels: []TranscriptPanel{}, Speakers: []string{}},
+		},
+		{
+			// xkcd #1, Barrel - Part 1
+			"Scenes And Dialogue",
+			"[[A boy sits in a barrel which is floating in an ocean.]]\nBoy: I wonder where I'll float next?\n[[The barrel drifts into the distance. Nothing else can be seen.]]\n{{Alt: Don't we all.}}",
+			Transcript{
+				Panels: []TranscriptPanel{
+					panel(
+						TranscriptElement{Kind: "scene", Text: "A boy sits in a barrel which is floating in an ocean."},
+						TranscriptElement{Kind: "line", Speaker: "Boy", Text: "I wonder where I'll float next?"},
+					),
+					panel(TranscriptElement{Kind: "scene", Text: "The barrel drifts into the distance. Nothing else can be seen."}),
+				},
+				Speakers:  []string{"Boy"},
+				TitleText: "Don't we all.",
+			},
+		},
+		{
+			// xkcd #40, Light
+			"Narration",
+			"[[A crowd of figures stand around in the dark. One figure is illuminated by a beam of light.]]\nIn a dark and confusing world, you burn brightly. I never feel lost.\n{{Alt-text: Like a beacon.}}",
+			Transcript{
+				Panels: []TranscriptPanel{
+					panel(
+						TranscriptElement{Kind: "scene", Text: "A crowd of figures stand around in the dark. One figure is illuminated by a beam of light."},
+						TranscriptElement{Kind: "text", Text: "In a dark and confusing world, you burn brightly. I never feel lost."},
+					),
+				},
+				Speakers:  []string{},
+				TitleText: "Like a beacon.",
+			},
+		},
+		{
+			// xkcd #41, Tree Cutting
+			"Speaker With Several Words",
+			"[[A tree holding a chainsaw over a recently cut-down tree.]]\nI found this in one of my high-school notebooks. I think I drew it just to take revenge on people snooping through my stuff.\nCut-down tree: WELL, YOU STUMPED ME...\n{{I don't want to talk about it}}",
+			Transcript{
+				Panels: []TranscriptPanel{
+					panel(
+						TranscriptElement{Kind: "scene", Text: "A tree holding a chainsaw over a recently cut-down tree."},
+						TranscriptElement{Kind: "text", Text: "I found this in one of my high-school notebooks. I think I drew it just to take revenge on people snooping through my stuff."},
+						TranscriptElement{Kind: "line", Speaker: "Cut-down tree", Text: "WELL, YOU STUMPED ME..."},
+					),
+				},
+				Speakers:  []string{"Cut-down tree"},
+				TitleText: "I don't want to talk about it",
+			},
+		},
+		{
+			// xkcd #303, Compiling
+			"Caption Ending With A Colon",
+			"The #1 programmer excuse for legitimately slacking off:\n\"My code's compiling.\"\n[[Two stickfigures sword-fighting on office chairs.]]\nManager: Hey! Get back to work!\nProgrammer: Compiling!\nManager: Oh. Carry on.\n{{title text: 'Are you stealing those LCDs?' 'Yeah, but I'm doing it while my code compiles.'}}",
+			Transcript{
+				Panels: []TranscriptPanel{
+					panel(
+						TranscriptElement{Kind: "text", Text: "The #1 programmer excuse for legitimately slacking off:"},
+						TranscriptElement{Kind: "text", Text: "\"My code's compiling.\""},
+					),
+					panel(
+						TranscriptElement{Kind: "scene", Text: "Two stickfigures sword-fighting on office chairs."},
+						TranscriptElement{Kind: "line", Speaker: "Manager", Text: "Hey! Get back to work!"},
+						TranscriptElement{Kind: "line", Speaker: "Programmer", Text: "Compiling!"},
+						TranscriptElement{Kind: "line", Speaker: "Manager", Text: "Oh. Carry on."},
+					),
+				},
+				Speakers:  []string{"Manager", "Programmer"},
+				TitleText: "'Are you stealing those LCDs?' 'Yeah, but I'm doing it while my code compiles.'",
+			},
+		},
+		{
+			"Blank Lines And Inline Scenes",
+			"Megan: Look!\n\n[[Cueball turns around.]] [[He squints.]]\nCueball: Where?\nCueball: [[shrugging]] I don't\n   see anything.\nCueball:\nStill nothing.\nNote: 10:30 is not a speaker",
+			Transcript{
+				Panels: []TranscriptPanel{
+					panel(TranscriptElement{Kind: "line", Speaker: "Megan", Text: "Look!"}),
+					panel(
+						TranscriptElement{Kind: "scene", Text: "Cueball turns around."},
+						TranscriptElement{Kind: "scene", Text: "He squints."},
+						TranscriptElement{Kind: "line", Speaker: "Cueball", Text: "Where?"},
+						TranscriptElement{Kind: "scene", Text: "shrugging"},
+						TranscriptElement{Kind: "line", Speaker: "Cueball", Text: "I don't"},
+						TranscriptElement{Kind: "text", Text: "see anything."},
+						TranscriptElement{Kind: "line", Speaker: "Cueball", Text: "Still nothing."},
+						TranscriptElement{Kind: "line", Speaker: "Note", Text: "10:30 is not a speaker"},
+					),
+				},
+				Speakers: []string{"Megan", "Cueball", "Note"},
+			},
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			us.Equal(test.expectedOutput, ParseTranscript(test.input))
+		})
+	}
+}
+
+func (us *TranscriptUnitSuite) TestTranscriptEndpoints() {
+	conf := config.NewConfig()
+	conf.FetchWorkers = 2
+	controller := NewController(conf, NewFSSource("testdata"), nil)
+
+	router := gin.New()
+	router.GET("/comics", controller.GetComics)
+	router.GET("/comics/:num/transcript", controller.GetComicTranscript)
+
+	testCases := []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			"Transcript",
+			"/comics/42/transcript",
+			200,
+			`{"num":42,"panels":[{"elements":[{"kind":"text","text":"I just saved a bunch of money on my car insurance by threatening my agent with a golf club."}]}],"speakers":[],"title_text":"David did this"}`,
+		},
+		{
+			"Transcript As CSV",
+			"/comics/41/transcript?format=csv",
+			200,
+			"num,panel,kind,speaker,text\n41,1,scene,,A tree holding a chainsaw over a recently cut-down tree.\n41,1,text,,I found this in one of my high-school notebooks. I think I drew it just to take revenge on people snooping through my stuff.\n41,1,line,Cut-down tree,\"WELL, YOU STUMPED ME...\"\n",
+		},
+		{
+			"Missing Comic",
+			"/comics/404/transcript",
+			404,
+			`{"error":"testdata/404/info.0.json: comic not found"}`,
+		},
+		{
+			"Invalid Expand",
+			"/comics?start=40&end=42&expand=title",
+			400,
+			`{"error":"please make sure that 'expand' only contains 'transcript'"}`,
+		},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, test.path, nil)
+			us.Nil(err)
+			router.ServeHTTP(recorder, request)
+			us.Equal(test.expectedStatus, recorder.Code)
+			us.Equal(test.expectedResponse, recorder.Body.String())
+		})
+	}
+
+	// Only the expanded comics come with their structured transcript
+	for _, expand := range []bool{false, true} {
+		path := "/comics?start=40&end=42&month_parity=any&sort=num"
+		if expand {
+			path += "&expand=transcript"
+		}
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, path, nil)
+		us.Nil(err)
+		router.ServeHTTP(recorder, request)
+		us.Equal(http.StatusOK, recorder.Code)
+
+		var response comicsResponse
+		us.Nil(json.Unmarshal(recorder.Body.Bytes(), &response))
+		us.Require().Len(response.Comics, 3)
+		for _, comic := range response.Comics {
+			if expand {
+				us.Require().NotNil(comic.ParsedTranscript)
+				us.Equal(ParseTranscript(comic.Transcript), *comic.ParsedTranscript)
+			} else {
+				us.Nil(comic.ParsedTranscript)
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package httputil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MatchesETag returns whether the value of an 'If-None-Match' header matches the given entity tag,
+// using the weak comparison required by RFC 9110. The wildcard only matches an existing entity tag.
+func MatchesETag(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// AddVary adds the request header to the given 'Vary' header of a response, unless it is already there
+func AddVary(header http.Header, field string) {
+	for _, value := range header.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
@@ -0,0 +1,57 @@
+package httputil
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type HTTPUtilUnitSuite struct {
+	suite.Suite
+}
+
+func TestHTTPUtilUnitSuite(t *testing.T) {
+	suite.Run(t, &HTTPUtilUnitSuite{})
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *HTTPUtilUnitSuite) TestMatchesETag() {
+	testCases := []struct {
+		name           string
+		ifNoneMatch    string
+		etag           string
+		expectedOutput bool
+	}{
+		{"Same ETag", `"abc"`, `"abc"`, true},
+		{"Among Others", `"other", "abc"`, `"abc"`, true},
+		{"Weak Comparison", `W/"abc"`, `"abc"`, true},
+		{"Weak ETag", `"abc"`, `W/"abc"`, true},
+		{"Other ETag", `"other"`, `"abc"`, false},
+		{"Wildcard", " * ", `"abc"`, true},
+		{"Wildcard Without ETag", "*", "", false},
+		{"Empty Header", "", `"abc"`, false},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			us.Equal(test.expectedOutput, MatchesETag(test.ifNoneMatch, test.etag))
+		})
+	}
+}
+
+func (us *HTTPUtilUnitSuite) TestAddVary() {
+	header := http.Header{"Vary": {"Accept, Accept-Language"}}
+	AddVary(header, "accept")
+	AddVary(header, "Accept-Encoding")
+	AddVary(header, "Accept-Encoding")
+	us.Equal([]string{"Accept, Accept-Language", "Accept-Encoding"}, header.Values("Vary"))
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"os"
 	"time"
 
@@ -14,6 +15,9 @@ const (
 	ISO8601layout = "2006-01-02T15:04:05-0700"
 )
 
+// contextKey is the key under which the log entry of a request is stored in its context
+type contextKey struct{}
+
 // InitLogger creates a new Logrus logger instance
 func InitLogger(logLevel, logFormat string) (*log.Logger, error) {
 	level, err := log.ParseLevel(logLevel)
@@ -46,6 +50,12 @@ func JSONLogger(logger *log.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Start timer
 		start := time.Now()
+		// Make a log entry describing the request available to the handlers
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), logger.WithFields(log.Fields{
+			"client_ip": util.GetClientIP(c),
+			"url":       c.Request.URL.Path,
+			"method":    c.Request.Method,
+		})))
 		// Process Request
 		c.Next()
 		// Stop timer
@@ -70,3 +80,16 @@ func JSONLogger(logger *log.Logger) gin.HandlerFunc {
 		}
 	}
 }
+
+// NewContext returns a copy of the parent context that carries the given log entry
+func NewContext(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, entry)
+}
+
+// FromContext returns the log entry carried by the context, or an entry of the standard logger if there is none
+func FromContext(ctx context.Context) *log.Entry {
+	if entry, ok := ctx.Value(contextKey{}).(*log.Entry); ok {
+		return entry
+	}
+	return log.NewEntry(log.StandardLogger())
+}
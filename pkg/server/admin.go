@@ -0,0 +1,26 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/controller"
+)
+
+// RequireAdminToken is a Gin handler function that only lets through the requests carrying the given
+// token as 'Authorization: Bearer <token>'. Every request is rejected when the token is empty, so that
+// the administration endpoints are never left open by mistake.
+func RequireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			controller.AbortWithError(c, http.StatusUnauthorized, errors.New("please make sure that the request carries a valid admin token"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type AdminUnitSuite struct {
+	suite.Suite
+}
+
+func TestAdminUnitSuite(t *testing.T) {
+	suite.Run(t, &AdminUnitSuite{})
+}
+
+// request performs a DELETE request on a router whose administration endpoint requires the given token
+func (us *AdminUnitSuite) request(token, authorization string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.DELETE("/admin/cache", RequireAdminToken(token), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"purged": 0})
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodDelete, "/admin/cache", nil)
+	us.Require().Nil(err)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *AdminUnitSuite) TestRequireAdminToken() {
+	testCases := []struct {
+		name           string
+		token          string
+		authorization  string
+		expectedStatus int
+	}{
+		{"Valid Token", "secret", "Bearer secret", 200},
+		{"Missing Token", "secret", "", 401},
+		{"Wrong Token", "secret", "Bearer guess", 401},
+		{"Other Scheme", "secret", "Basic secret", 401},
+		{"No Configured Token", "", "Bearer ", 401},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := us.request(test.token, test.authorization)
+			us.Equal(test.expectedStatus, recorder.Code)
+			if test.expectedStatus == http.StatusUnauthorized {
+				us.Equal(`Bearer realm="admin"`, recorder.Header().Get("WWW-Authenticate"))
+				us.Equal(`{"error":"please make sure that the request carries a valid admin token"}`, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/httputil"
+)
+
+// compressionEncodings are the content codings that the responses may be compressed with, from the most
+// to the least preferred one when the client accepts several of them equally
+var compressionEncodings = []string{"br", "gzip", "deflate"}
+
+// encoder is a compressing writer that can be flushed and reused
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// compressor holds the settings of the compression middleware along with the pools of encoders, one
+// per content coding
+type compressor struct {
+	minSize       int
+	excludedTypes []string
+	pools         map[string]*sync.Pool
+}
+
+// newCompressor returns a compressor whose encoders compress at the given level, which must be valid
+func newCompressor(level, minSize int, excludedTypes []string) *compressor {
+	// The level is in between 1 and 9, which is valid for every encoder, hence the errors are ignored
+	return &compressor{
+		minSize:       minSize,
+		excludedTypes: excludedTypes,
+		pools: map[string]*sync.Pool{
+			"br": {New: func() any {
+				return brotli.NewWriterLevel(io.Discard, level)
+			}},
+			"gzip": {New: func() any {
+				w, _ := gzip.NewWriterLevel(io.Discard, level)
+				return w
+			}},
+			"deflate": {New: func() any {
+				w, _ := zlib.NewWriterLevel(io.Discard, level)
+				return w
+			}},
+		},
+	}
+}
+
+// excluded returns whether responses of the given media type are never compressed, e.g. images that
+// already are
+func (cp *compressor) excluded(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	for _, prefix := range cp.excludedTypes {
+		if prefix != "" && strings.HasPrefix(mediaType, strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compression is a Gin handler function that compresses the responses with the content coding that the
+// client prefers among brotli, gzip and deflate, according to its 'Accept-Encoding' header. Responses
+// smaller than 'minSize' bytes, whose media type starts with one of the excluded ones, or that are
+// already encoded are sent as they are, while streams are compressed as they are flushed. The entity
+// tag of the compressed responses is weakened, as their bytes differ from the uncompressed ones.
+// Compression is disabled when the level is 0.
+func Compression(level, minSize int, excludedTypes []string) (gin.HandlerFunc, error) {
+	if level < 0 || level > 9 {
+		return nil, errors.New("please make sure that the compression level is in between 0 and 9")
+	}
+	if level == 0 {
+		return func(c *gin.Context) { c.Next() }, nil
+	}
+	cp := newCompressor(level, minSize, excludedTypes)
+
+	return func(c *gin.Context) {
+		original := c.Writer
+		w := &compressWriter{
+			ResponseWriter: original,
+			compressor:     cp,
+			encoding:       negotiateEncoding(c.GetHeader("Accept-Encoding")),
+			status:         original.Status(),
+		}
+		c.Writer = w
+		c.Next()
+		c.Writer = original
+		w.finish()
+	}, nil
+}
+
+// compressWriter holds the beginning of the response back until it is large enough to be worth
+// compressing, flushed, or complete, and then either compresses the response or passes it through
+type compressWriter struct {
+	gin.ResponseWriter
+	compressor *compressor
+	// encoding is the negotiated content coding, empty when the client does not accept any of them
+	encoding string
+	status   int
+	buf      bytes.Buffer
+	// size is the number of bytes of the uncompressed body
+	size int
+	// touched is set once the handlers set the status code or wrote the body
+	touched bool
+	// committed is set once the headers were written, after which the response is either compressed
+	// through enc or passed through
+	committed bool
+	enc       encoder
+	out       *countingWriter
+}
+
+// WriteHeader records the status code of the response until the headers are written
+func (w *compressWriter) WriteHeader(code int) {
+	if w.committed {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	w.touched = true
+}
+
+// WriteHeaderNow does nothing until the headers are written, as they depend on the body
+func (w *compressWriter) WriteHeaderNow() {
+	if w.committed {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Write buffers the body until it reaches the minimum size, and compresses it from then on
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.touched = true
+	w.size += len(data)
+	if !w.committed {
+		w.buf.Write(data)
+		if w.buf.Len() < w.compressor.minSize {
+			return len(data), nil
+		}
+		if err := w.commit(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString is the same as Write
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Status returns the status code of the response
+func (w *compressWriter) Status() int {
+	if w.committed {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+// Size returns the number of bytes of the uncompressed body that were written so far
+func (w *compressWriter) Size() int {
+	return w.size
+}
+
+// Written returns whether anything was written yet
+func (w *compressWriter) Written() bool {
+	return w.committed || w.buf.Len() > 0
+}
+
+// Flush sends what was written so far to the client, compressing the stream regardless of its size
+func (w *compressWriter) Flush() {
+	if !w.committed {
+		if err := w.commit(true); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// commit writes the headers, deciding whether the response is compressed, along with what was buffered
+func (w *compressWriter) commit(compress bool) error {
+	w.committed = true
+	header := w.Header()
+	if w.compressible(header) {
+		// Caches must not serve a compressed response to clients that cannot decode it
+		httputil.AddVary(header, "Accept-Encoding")
+		if compress && w.encoding != "" {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				header.Set("ETag", "W/"+etag)
+			}
+			w.out = &countingWriter{w: w.ResponseWriter}
+			w.enc = w.compressor.pools[w.encoding].Get().(encoder)
+			w.enc.Reset(w.out)
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// compressible returns whether the response may be compressed, regardless of its size and of the
+// encodings accepted by the client
+func (w *compressWriter) compressible(header http.Header) bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" || strings.Contains(strings.ToLower(header.Get("Cache-Control")), "no-transform") {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" && w.buf.Len() > 0 {
+		// The body cannot be sniffed by net/http once it is compressed
+		contentType = http.DetectContentType(w.buf.Bytes())
+		header.Set("Content-Type", contentType)
+	}
+	return !w.compressor.excluded(contentType)
+}
+
+// finish writes the rest of the response once the handlers are done, and records how much was saved
+func (w *compressWriter) finish() {
+	if !w.committed {
+		// Responses that were not written at all are left to gin, e.g. unknown routes
+		if !w.touched {
+			return
+		}
+		// The response is smaller than the minimum size
+		_ = w.commit(false)
+	}
+	if w.enc == nil {
+		return
+	}
+
+	_ = w.enc.Close()
+	w.compressor.pools[w.encoding].Put(w.enc)
+	compressedResponses.WithLabelValues(w.encoding).Inc()
+	compressionInputBytes.WithLabelValues(w.encoding).Add(float64(w.size))
+	if saved := w.size - w.out.n; saved > 0 {
+		compressionSavedBytes.WithLabelValues(w.encoding).Add(float64(saved))
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+// Write writes the data to the underlying writer, counting the bytes
+func (cw *countingWriter) Write(data []byte) (int, error) {
+	n, err := cw.w.Write(data)
+	cw.n += n
+	return n, err
+}
+
+// negotiateEncoding returns the supported content coding that the client prefers according to its
+// 'Accept-Encoding' header, or an empty string if it does not accept any of them
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	wildcard := 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "x-gzip" {
+			name = "gzip"
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if name == "*" {
+			wildcard = quality
+		} else {
+			qualities[name] = quality
+		}
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range compressionEncodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = wildcard
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type CompressionUnitSuite struct {
+	suite.Suite
+	router *gin.Engine
+	large  string
+}
+
+func TestCompressionUnitSuite(t *testing.T) {
+	suite.Run(t, &CompressionUnitSuite{})
+}
+
+func (us *CompressionUnitSuite) SetupSuite() {
+	compression, err := Compression(5, 64, []string{"image/"})
+	us.Require().Nil(err)
+	conditional, err := ConditionalRequests(300, nil)
+	us.Require().Nil(err)
+	us.large = `{"transcript":"` + strings.Repeat("[[Cueball stands next to Megan.]] ", 20) + `"}`
+
+	us.router = gin.New()
+	us.router.Use(compression, conditional)
+	us.router.GET("/comics", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(us.large))
+	})
+	us.router.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	us.router.GET("/comics/42/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(us.large))
+	})
+	us.router.GET("/comics/42/archive", func(c *gin.Context) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "application/json", []byte(us.large))
+	})
+	us.router.GET("/comics/stream", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/event-stream")
+		_, _ = c.Writer.WriteString("data: 40\n\n")
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString("data: 41\n\n")
+	})
+}
+
+// request performs a GET request on the router with the given 'Accept-Encoding' header
+func (us *CompressionUnitSuite) request(path, acceptEncoding string, header http.Header) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, path, nil)
+	us.Require().Nil(err)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if acceptEncoding != "" {
+		request.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	us.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// decode returns the body of the response, decompressed according to its 'Content-Encoding' header
+func (us *CompressionUnitSuite) decode(recorder *httptest.ResponseRecorder) string {
+	var (
+		reader io.Reader
+		err    error
+	)
+	switch recorder.Header().Get("Content-Encoding") {
+	case "br":
+		reader = brotli.NewReader(recorder.Body)
+	case "gzip":
+		reader, err = gzip.NewReader(recorder.Body)
+	case "deflate":
+		reader, err = zlib.NewReader(recorder.Body)
+	default:
+		reader = recorder.Body
+	}
+	us.Require().Nil(err)
+	body, err := io.ReadAll(reader)
+	us.Require().Nil(err)
+	return string(body)
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *CompressionUnitSuite) TestCompression() {
+	testCases := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		expectedEncoding string
+		expectedVary     string
+	}{
+		{"Brotli", "/comics", "gzip, deflate, br", "br", "Accept-Encoding"},
+		{"Gzip", "/comics", "gzip", "gzip", "Accept-Encoding"},
+		{"Deflate", "/comics", "deflate", "deflate", "Accept-Encoding"},
+		{"No Accept-Encoding", "/comics", "", "", "Accept-Encoding"},
+		{"Identity", "/comics", "identity", "", "Accept-Encoding"},
+		{"Small Response", "/ping", "gzip", "", "Accept-Encoding"},
+		{"Excluded Type", "/comics/42/image", "gzip", "", ""},
+		{"Already Encoded", "/comics/42/archive", "br", "gzip", ""},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := us.request(test.path, test.acceptEncoding, nil)
+			us.Equal(http.StatusOK, recorder.Code)
+			us.Equal(test.expectedEncoding, recorder.Header().Get("Content-Encoding"))
+			us.Equal(test.expectedVary, recorder.Header().Get("Vary"))
+			if test.path == "/ping" {
+				us.Equal("pong", recorder.Body.String())
+			} else if test.path == "/comics/42/archive" {
+				us.Equal(us.large, recorder.Body.String())
+			} else {
+				us.Equal(us.large, us.decode(recorder))
+			}
+			if test.expectedEncoding != "" && test.path == "/comics" {
+				us.Less(recorder.Body.Len(), len(us.large))
+			}
+		})
+	}
+}
+
+func (us *CompressionUnitSuite) TestConditionalRequests() {
+	// The entity tag of the compressed response is weak, and still validates the uncompressed one
+	etag := us.request("/comics", "", nil).Header().Get("ETag")
+	us.Regexp(`^"[0-9a-f]{32}"$`, etag)
+	compressed := us.request("/comics", "gzip", nil)
+	us.Equal("W/"+etag, compressed.Header().Get("ETag"))
+
+	recorder := us.request("/comics", "gzip", http.Header{"If-None-Match": {compressed.Header().Get("ETag")}})
+	us.Equal(http.StatusNotModified, recorder.Code)
+	us.Empty(recorder.Body.String())
+	us.Empty(recorder.Header().Get("Content-Encoding"))
+
+	recorder = us.request("/comics", "", http.Header{"If-None-Match": {compressed.Header().Get("ETag")}})
+	us.Equal(http.StatusNotModified, recorder.Code)
+}
+
+func (us *CompressionUnitSuite) TestStreams() {
+	recorder := us.request("/comics/stream", "gzip", nil)
+	us.Equal(http.StatusOK, recorder.Code)
+	us.True(recorder.Flushed)
+	us.Equal("gzip", recorder.Header().Get("Content-Encoding"))
+	us.Equal("data: 40\n\ndata: 41\n\n", us.decode(recorder))
+}
+
+func (us *CompressionUnitSuite) TestMetrics() {
+	responses := testutil.ToFloat64(compressedResponses.WithLabelValues("gzip"))
+	input := testutil.ToFloat64(compressionInputBytes.WithLabelValues("gzip"))
+	saved := testutil.ToFloat64(compressionSavedBytes.WithLabelValues("gzip"))
+
+	recorder := us.request("/comics", "gzip", nil)
+	us.Equal(responses+1, testutil.ToFloat64(compressedResponses.WithLabelValues("gzip")))
+	us.Equal(input+float64(len(us.large)), testutil.ToFloat64(compressionInputBytes.WithLabelValues("gzip")))
+	us.Equal(saved+float64(len(us.large)-recorder.Body.Len()), testutil.ToFloat64(compressionSavedBytes.WithLabelValues("gzip")))
+}
+
+func (us *CompressionUnitSuite) TestNegotiateEncoding() {
+	testCases := []struct {
+		name             string
+		acceptEncoding   string
+		expectedEncoding string
+	}{
+		{"Empty", "", ""},
+		{"Unsupported", "compress", ""},
+		{"Single", "gzip", "gzip"},
+		{"Legacy Name", "x-gzip", "gzip"},
+		{"Server Preference", "deflate, gzip, br", "br"},
+		{"Quality", "br;q=0.5, gzip;q=0.8", "gzip"},
+		{"Refused", "br;q=0, gzip;q=0", ""},
+		{"Wildcard", "*", "br"},
+		{"Wildcard With Refusal", "*;q=0.5, br;q=0", "gzip"},
+		{"Case And Spaces", " GZIP ; Q=1 ", "gzip"},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			us.Equal(test.expectedEncoding, negotiateEncoding(test.acceptEncoding))
+		})
+	}
+}
+
+func (us *CompressionUnitSuite) TestLevel() {
+	testCases := []struct {
+		name          string
+		level         int
+		expectedError error
+	}{
+		{"Disabled", 0, nil},
+		{"Fastest", 1, nil},
+		{"Smallest", 9, nil},
+		{"Negative", -1, errors.New("please make sure that the compression level is in between 0 and 9")},
+		{"Too High", 10, errors.New("please make sure that the compression level is in between 0 and 9")},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			_, err := Compression(test.level, 0, nil)
+			us.Equal(test.expectedError, err)
+		})
+	}
+
+	// Responses are left untouched when the compression is disabled
+	compression, err := Compression(0, 0, nil)
+	us.Require().Nil(err)
+	router := gin.New()
+	router.Use(compression)
+	router.GET("/comics", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(us.large)) })
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/comics", nil)
+	us.Require().Nil(err)
+	request.Header.Set("Accept-Encoding", "gzip")
+	router.ServeHTTP(recorder, request)
+	us.Empty(recorder.Header().Get("Content-Encoding"))
+	us.True(bytes.Equal([]byte(us.large), recorder.Body.Bytes()))
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/httputil"
+)
+
+// bufferedWriter holds the response back until the handlers are done, so that it can be validated
+// as a whole. Responses that are flushed by the handlers, i.e. streams, are passed through as they are.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+	// touched is set once the handlers set the status code or wrote the body
+	touched   bool
+	streaming bool
+}
+
+// WriteHeader records the status code of the response
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.streaming {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	w.touched = true
+}
+
+// WriteHeaderNow does nothing until the response is streamed, as the status code may still change
+func (w *bufferedWriter) WriteHeaderNow() {
+	if w.streaming {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Write buffers the body of the response
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(data)
+	}
+	w.touched = true
+	return w.body.Write(data)
+}
+
+// WriteString buffers the body of the response
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.WriteString(s)
+	}
+	w.touched = true
+	return w.body.WriteString(s)
+}
+
+// Status returns the status code of the response
+func (w *bufferedWriter) Status() int {
+	if w.streaming {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+// Size returns the number of bytes of the body that were written so far
+func (w *bufferedWriter) Size() int {
+	if w.streaming {
+		return w.ResponseWriter.Size()
+	}
+	return w.body.Len()
+}
+
+// Written returns whether anything was written yet
+func (w *bufferedWriter) Written() bool {
+	return w.streaming || w.body.Len() > 0
+}
+
+// Flush writes what was buffered so far and turns the response into a stream
+func (w *bufferedWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		w.ResponseWriter.WriteHeader(w.status)
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// cachePolicy describes the time that clients may cache the responses for, by route
+type cachePolicy struct {
+	defaultMaxAge int
+	maxAges       map[string]int
+}
+
+// newCachePolicy returns the cache policy made of the default max-age and of the given overrides, each
+// of them formatted as ROUTE=SECONDS where ROUTE is a path as registered in the router, e.g. /comics/:num
+func newCachePolicy(defaultMaxAge int, overrides []string) (cachePolicy, error) {
+	policy := cachePolicy{defaultMaxAge: defaultMaxAge, maxAges: make(map[string]int, len(overrides))}
+	for _, override := range overrides {
+		if override == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(override, "=")
+		maxAge, err := strconv.Atoi(value)
+		if !ok || err != nil || maxAge < 0 || !strings.HasPrefix(route, "/") {
+			return policy, fmt.Errorf("please make sure that the route max-age '%s' is formatted as ROUTE=SECONDS", override)
+		}
+		policy.maxAges[route] = maxAge
+	}
+	return policy, nil
+}
+
+// CacheControl returns the 'Cache-Control' header of the responses of the given route. Responses whose
+// max-age is zero must be revalidated every time.
+func (p cachePolicy) CacheControl(route string) string {
+	maxAge, ok := p.maxAges[route]
+	if !ok {
+		maxAge = p.defaultMaxAge
+	}
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", maxAge)
+}
+
+// ConditionalRequests is a Gin handler function that makes the successful responses to GET and HEAD
+// requests cacheable. A strong 'ETag' is computed over the rendered response unless the handler set
+// one, the 'Cache-Control' header is set according to the max-age of the route unless the handler set
+// one, and the clients revalidating their copy through 'If-None-Match' or 'If-Modified-Since', the
+// latter being compared with the 'Last-Modified' header set by the handler, are answered with a
+// '304 Not Modified'. Partial responses, which carry a 'Warning', are never stored so that they are not
+// served once upstream is back, and lose their 'Last-Modified' header since the comics they lack may be
+// more recent. Streamed responses are left untouched.
+func ConditionalRequests(defaultMaxAge int, routeMaxAges []string) (gin.HandlerFunc, error) {
+	policy, err := newCachePolicy(defaultMaxAge, routeMaxAges)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &bufferedWriter{ResponseWriter: original, status: original.Status()}
+		c.Writer = w
+		c.Next()
+		c.Writer = original
+		// Responses that were neither written nor streamed are left to gin, e.g. unknown routes
+		if w.streaming || !w.touched {
+			return
+		}
+
+		header := original.Header()
+		if w.status == http.StatusOK {
+			if header.Get("ETag") == "" {
+				sum := sha256.Sum256(w.body.Bytes())
+				header.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+			}
+			switch {
+			case header.Get("Warning") != "":
+				header.Set("Cache-Control", "no-store")
+				header.Del("Last-Modified")
+			case header.Get("Cache-Control") == "":
+				header.Set("Cache-Control", policy.CacheControl(c.FullPath()))
+			}
+			if notModified(c.Request, header) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				original.WriteHeader(http.StatusNotModified)
+				original.WriteHeaderNow()
+				return
+			}
+		}
+
+		original.WriteHeader(w.status)
+		original.WriteHeaderNow()
+		_, _ = original.Write(w.body.Bytes())
+	}, nil
+}
+
+// notModified returns whether the copy of the response held by the client is still valid, as described
+// by RFC 9110, i.e. 'If-Modified-Since' is only looked at when 'If-None-Match' is absent
+func notModified(r *http.Request, header http.Header) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return httputil.MatchesETag(ifNoneMatch, header.Get("ETag"))
+	}
+
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type ConditionalUnitSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func TestConditionalUnitSuite(t *testing.T) {
+	suite.Run(t, &ConditionalUnitSuite{})
+}
+
+func (us *ConditionalUnitSuite) SetupSuite() {
+	conditional, err := ConditionalRequests(300, []string{"/comics/random=0", "/comics/:num=86400"})
+	us.Require().Nil(err)
+
+	us.router = gin.New()
+	us.router.Use(conditional)
+	us.router.GET("/comics", func(c *gin.Context) {
+		c.Header("Last-Modified", "Sun, 01 Jan 2006 00:00:00 GMT")
+		c.JSON(http.StatusOK, gin.H{"comics": []int{40, 41, 42}})
+	})
+	us.router.GET("/comics/random", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"num": 42})
+	})
+	us.router.GET("/stats", func(c *gin.Context) {
+		c.Header("Warning", `199 - "partial"`)
+		c.Header("Last-Modified", "Sun, 01 Jan 2006 00:00:00 GMT")
+		c.Header("Cache-Control", "public, max-age=60")
+		c.JSON(http.StatusOK, gin.H{"partial": true})
+	})
+	getComic := func(c *gin.Context) {
+		if c.Param("num") != "42" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "comic not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"num": 42})
+	}
+	us.router.GET("/comics/:num", getComic)
+	us.router.HEAD("/comics/:num", getComic)
+	us.router.GET("/comics/:num/image", func(c *gin.Context) {
+		c.Header("ETag", `"image"`)
+		c.Header("Cache-Control", "public, max-age=60")
+		c.Data(http.StatusOK, "image/png", []byte("image"))
+	})
+	us.router.GET("/comics/stream", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString("data: 40\n\n")
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString("data: 41\n\n")
+	})
+	us.router.POST("/comics", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"num": 43})
+	})
+}
+
+// request performs a request on the router with the given headers
+func (us *ConditionalUnitSuite) request(method, path string, header http.Header) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(method, path, nil)
+	us.Require().Nil(err)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	us.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *ConditionalUnitSuite) TestCacheHeaders() {
+	recorder := us.request(http.MethodGet, "/comics", nil)
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal(`{"comics":[40,41,42]}`, recorder.Body.String())
+	us.Regexp(`^"[0-9a-f]{32}"$`, recorder.Header().Get("ETag"))
+	us.Equal("public, max-age=300", recorder.Header().Get("Cache-Control"))
+
+	// The entity tag only depends on the payload
+	us.Equal(recorder.Header().Get("ETag"), us.request(http.MethodGet, "/comics", nil).Header().Get("ETag"))
+	us.NotEqual(recorder.Header().Get("ETag"), us.request(http.MethodGet, "/comics/42", nil).Header().Get("ETag"))
+
+	testCases := []struct {
+		name                 string
+		method               string
+		path                 string
+		expectedStatus       int
+		expectedETag         bool
+		expectedCacheControl string
+	}{
+		{"Route Override", http.MethodGet, "/comics/42", 200, true, "public, max-age=86400"},
+		{"Revalidated Route", http.MethodGet, "/comics/random", 200, true, "no-cache"},
+		{"Handler Headers", http.MethodGet, "/comics/42/image", 200, true, "public, max-age=60"},
+		{"Partial Response", http.MethodGet, "/stats", 200, true, "no-store"},
+		{"Head", http.MethodHead, "/comics/42", 200, true, "public, max-age=86400"},
+		{"Error", http.MethodGet, "/comics/404", 404, false, ""},
+		{"Unknown Route", http.MethodGet, "/feeds", 404, false, ""},
+		{"Other Method", http.MethodPost, "/comics", 201, false, ""},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := us.request(test.method, test.path, nil)
+			us.Equal(test.expectedStatus, recorder.Code)
+			us.Equal(test.expectedETag, recorder.Header().Get("ETag") != "")
+			us.Equal(test.expectedCacheControl, recorder.Header().Get("Cache-Control"))
+		})
+	}
+	us.Equal(`"image"`, us.request(http.MethodGet, "/comics/42/image", nil).Header().Get("ETag"))
+}
+
+func (us *ConditionalUnitSuite) TestConditionalRequests() {
+	etag := us.request(http.MethodGet, "/comics", nil).Header().Get("ETag")
+
+	testCases := []struct {
+		name           string
+		header         http.Header
+		expectedStatus int
+	}{
+		{"Matching ETag", http.Header{"If-None-Match": {etag}}, 304},
+		{"Matching ETag Among Others", http.Header{"If-None-Match": {`"other", W/` + etag}}, 304},
+		{"Any ETag", http.Header{"If-None-Match": {"*"}}, 304},
+		{"Other ETag", http.Header{"If-None-Match": {`"other"`}}, 200},
+		{"Not Modified Since", http.Header{"If-Modified-Since": {"Mon, 02 Jan 2006 00:00:00 GMT"}}, 304},
+		{"Not Modified Since Exactly", http.Header{"If-Modified-Since": {"Sun, 01 Jan 2006 00:00:00 GMT"}}, 304},
+		{"Modified Since", http.Header{"If-Modified-Since": {"Sat, 31 Dec 2005 00:00:00 GMT"}}, 200},
+		{"Invalid Date", http.Header{"If-Modified-Since": {"yesterday"}}, 200},
+		{"ETag Takes Precedence", http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {"Mon, 02 Jan 2006 00:00:00 GMT"}}, 200},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := us.request(http.MethodGet, "/comics", test.header)
+			us.Equal(test.expectedStatus, recorder.Code)
+			us.Equal(etag, recorder.Header().Get("ETag"))
+			if test.expectedStatus == http.StatusNotModified {
+				us.Empty(recorder.Body.String())
+				us.Empty(recorder.Header().Get("Content-Type"))
+				us.Equal("public, max-age=300", recorder.Header().Get("Cache-Control"))
+			} else {
+				us.Equal(`{"comics":[40,41,42]}`, recorder.Body.String())
+			}
+		})
+	}
+
+	// Responses without a Last-Modified header are never considered as not modified by date
+	recorder := us.request(http.MethodGet, "/comics/42", http.Header{"If-Modified-Since": {"Mon, 02 Jan 2006 00:00:00 GMT"}})
+	us.Equal(http.StatusOK, recorder.Code)
+
+	// Neither are the partial responses, whose modification date cannot be trusted
+	recorder = us.request(http.MethodGet, "/stats", http.Header{"If-Modified-Since": {"Mon, 02 Jan 2006 00:00:00 GMT"}})
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Empty(recorder.Header().Get("Last-Modified"))
+}
+
+func (us *ConditionalUnitSuite) TestStreams() {
+	recorder := us.request(http.MethodGet, "/comics/stream", nil)
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal("data: 40\n\ndata: 41\n\n", recorder.Body.String())
+	us.True(recorder.Flushed)
+	us.Empty(recorder.Header().Get("ETag"))
+	us.Empty(recorder.Header().Get("Cache-Control"))
+}
+
+func (us *ConditionalUnitSuite) TestCachePolicy() {
+	testCases := []struct {
+		name          string
+		overrides     []string
+		expectedError error
+	}{
+		{"No Overrides", nil, nil},
+		{"Empty Override", []string{""}, nil},
+		{"Overrides", []string{"/comics=10", "/comics/:num=0"}, nil},
+		{"Missing Max-Age", []string{"/comics"}, errors.New("please make sure that the route max-age '/comics' is formatted as ROUTE=SECONDS")},
+		{"Negative Max-Age", []string{"/comics=-1"}, errors.New("please make sure that the route max-age '/comics=-1' is formatted as ROUTE=SECONDS")},
+		{"Relative Route", []string{"comics=10"}, errors.New("please make sure that the route max-age 'comics=10' is formatted as ROUTE=SECONDS")},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			_, err := ConditionalRequests(60, test.overrides)
+			us.Equal(test.expectedError, err)
+		})
+	}
+}
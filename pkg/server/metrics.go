@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsNamespace is the prefix of every metric exposed by the server
+const metricsNamespace = "api"
+
+var (
+	compressedResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "compression",
+		Name:      "responses_total",
+		Help:      "Number of responses that were compressed, by content coding.",
+	}, []string{"encoding"})
+	compressionInputBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "compression",
+		Name:      "input_bytes_total",
+		Help:      "Number of bytes of the compressed responses before their compression, by content coding.",
+	}, []string{"encoding"})
+	compressionSavedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "compression",
+		Name:      "saved_bytes_total",
+		Help:      "Number of bytes that were saved by compressing the responses, by content coding.",
+	}, []string{"encoding"})
+	rateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "rate_limit",
+		Name:      "rejected_requests_total",
+		Help:      "Number of requests that were rejected for exceeding the rate limit, by route.",
+	}, []string{"route"})
+)
+
+// RegisterMetrics registers the metrics of the server with the given registerer, ignoring the ones
+// that have already been registered
+func RegisterMetrics(reg prometheus.Registerer) error {
+	collectors := []prometheus.Collector{
+		compressedResponses,
+		compressionInputBytes,
+		compressionSavedBytes,
+		rateLimitedRequests,
+	}
+	for _, collector := range collectors {
+		if err := reg.Register(collector); err != nil {
+			var registered prometheus.AlreadyRegisteredError
+			if !errors.As(err, &registered) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsemre/go-api-k8s/pkg/controller"
+)
+
+const (
+	// rateLimitKeyIP identifies the clients by their IP address
+	rateLimitKeyIP = "ip"
+	// rateLimitKeyAPIKey identifies the clients by their API key
+	rateLimitKeyAPIKey = "api-key"
+	// rateLimitKeyHeader identifies the clients by the value of a header, written as 'header:NAME'
+	rateLimitKeyHeader = "header:"
+	// apiKeyHeader is the header holding the API key of the clients
+	apiKeyHeader = "X-API-Key"
+)
+
+// rateLimit is the rate at which the tokens of a bucket are refilled, and the size of the bucket
+type rateLimit struct {
+	perMinute int
+	burst     int
+}
+
+// unlimited returns whether the requests are not limited at all
+func (l rateLimit) unlimited() bool {
+	return l.perMinute <= 0
+}
+
+// parseRouteLimits parses the rate limit overrides, each of them formatted as ROUTE=RATE[/BURST] where
+// ROUTE is a path as registered in the router and the burst defaults to the given one
+func parseRouteLimits(overrides []string, defaultBurst int) (map[string]rateLimit, error) {
+	limits := make(map[string]rateLimit, len(overrides))
+	for _, override := range overrides {
+		if override == "" {
+			continue
+		}
+		invalid := fmt.Errorf("please make sure that the route rate limit '%s' is formatted as ROUTE=RATE[/BURST]", override)
+		route, value, ok := strings.Cut(override, "=")
+		if !ok || !strings.HasPrefix(route, "/") {
+			return nil, invalid
+		}
+		rate, burst, hasBurst := strings.Cut(value, "/")
+		limit := rateLimit{burst: defaultBurst}
+		var err error
+		if limit.perMinute, err = strconv.Atoi(rate); err != nil || limit.perMinute < 0 {
+			return nil, invalid
+		}
+		if hasBurst {
+			if limit.burst, err = strconv.Atoi(burst); err != nil || limit.burst < 1 {
+				return nil, invalid
+			}
+		}
+		limits[route] = limit
+	}
+	return limits, nil
+}
+
+// clientKeyFunc returns the function identifying the client of a request according to the given mode
+// and known keys, see RateLimit
+func clientKeyFunc(mode string, knownKeys []string) (func(c *gin.Context) string, error) {
+	var header string
+	switch {
+	case mode == rateLimitKeyIP:
+	case mode == rateLimitKeyAPIKey:
+		header = apiKeyHeader
+	case strings.HasPrefix(mode, rateLimitKeyHeader) && len(mode) > len(rateLimitKeyHeader):
+		header = mode[len(rateLimitKeyHeader):]
+	default:
+		return nil, errors.New("please make sure that the rate limit key is one of 'ip', 'api-key' or 'header:NAME'")
+	}
+	known := make(map[string]bool, len(knownKeys))
+	for _, key := range knownKeys {
+		if key != "" {
+			known[key] = true
+		}
+	}
+
+	return func(c *gin.Context) string {
+		// Unknown keys are ignored, as the clients would otherwise get a new bucket with every new key
+		if header != "" {
+			if value := c.GetHeader(header); known[value] {
+				return "key:" + value
+			}
+		}
+		// The forwarded headers are only looked at when they were set by one of the trusted proxies, as
+		// the clients could otherwise get a new bucket with every new address
+		return "ip:" + c.ClientIP()
+	}, nil
+}
+
+// RateLimit is a Gin handler function that limits the rate of the requests of every client with a token
+// bucket, refilled at 'perMinute' tokens per minute and holding at most 'burst' of them. The clients are
+// identified according to the key, i.e. 'ip' for their IP address, 'api-key' for the 'X-API-Key' header
+// or 'header:NAME' for any other header. Only the keys listed in 'knownKeys' are trusted, the clients
+// lacking the header or sending any other key being identified by their IP address as resolved by the
+// trusted proxies of the engine. The routes whose limit is overridden, formatted as ROUTE=RATE[/BURST]
+// with a rate of 0 disabling the limit, have buckets of their own. At most 'maxClients' buckets are
+// kept, the least recently used ones being forgotten first. The limited responses describe the state of the bucket in
+// the 'RateLimit-*' headers, and the rejected ones tell when to retry in the 'Retry-After' header.
+// Rate limiting is disabled when the rate is 0.
+func RateLimit(perMinute, burst int, key string, knownKeys, routeLimits []string, maxClients int) (gin.HandlerFunc, error) {
+	if perMinute < 0 || burst < 1 {
+		return nil, errors.New("please make sure that the rate limit is not negative and that the burst is positive")
+	}
+	if perMinute == 0 {
+		return func(c *gin.Context) { c.Next() }, nil
+	}
+	clientKey, err := clientKeyFunc(key, knownKeys)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := parseRouteLimits(routeLimits, burst)
+	if err != nil {
+		return nil, err
+	}
+	limiter := newRateLimiter(maxClients)
+	defaultLimit := rateLimit{perMinute: perMinute, burst: burst}
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		limit, overridden := routes[route]
+		if !overridden {
+			limit = defaultLimit
+			// The routes that are not overridden share the same bucket
+			route = ""
+		}
+		if limit.unlimited() {
+			c.Next()
+			return
+		}
+
+		allowed, remaining, reset, retryAfter := limiter.Take(clientKey(c)+" "+route, limit)
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(limit.burst))
+		header.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+		if !allowed {
+			rateLimitedRequests.WithLabelValues(c.FullPath()).Inc()
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			controller.AbortWithError(c, http.StatusTooManyRequests, fmt.Errorf("please make sure that no more than %d requests are made per minute", limit.perMinute))
+			return
+		}
+		c.Next()
+	}, nil
+}
+
+// ceilSeconds returns the duration in whole seconds, rounded up
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// rateLimiter holds the token buckets of the clients, bounded by the number of clients. A token
+// bucket that was forgotten is equivalent to a full one.
+type rateLimiter struct {
+	mu         sync.Mutex
+	maxClients int
+	buckets    map[string]*list.Element
+	// order holds the buckets from the most to the least recently used
+	order *list.List
+	now   func() time.Time
+}
+
+// tokenBucket is the bucket of a single client
+type tokenBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter returns a rate limiter keeping track of at most 'maxClients' clients, or of a single
+// one if it is not positive
+func newRateLimiter(maxClients int) *rateLimiter {
+	if maxClients < 1 {
+		maxClients = 1
+	}
+	return &rateLimiter{
+		maxClients: maxClients,
+		buckets:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Take takes a token from the bucket with the given key, and returns whether there was one along with
+// the number of whole tokens left, the time until the bucket is full again and, if there was no token,
+// the time until there is one
+func (rl *rateLimiter) Take(key string, limit rateLimit) (bool, int, time.Duration, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	var bucket *tokenBucket
+	if elem, ok := rl.buckets[key]; ok {
+		bucket = elem.Value.(*tokenBucket)
+		rl.order.MoveToFront(elem)
+	} else {
+		bucket = &tokenBucket{key: key, tokens: float64(limit.burst), updated: now}
+		rl.buckets[key] = rl.order.PushFront(bucket)
+		if rl.order.Len() > rl.maxClients {
+			oldest := rl.order.Back()
+			rl.order.Remove(oldest)
+			delete(rl.buckets, oldest.Value.(*tokenBucket).key)
+		}
+	}
+
+	perSecond := float64(limit.perMinute) / 60
+	bucket.tokens = math.Min(float64(limit.burst), bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond)
+	bucket.updated = now
+	var retryAfter time.Duration
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	} else {
+		retryAfter = secondsToDuration((1 - bucket.tokens) / perSecond)
+	}
+	return allowed, int(bucket.tokens), secondsToDuration((float64(limit.burst) - bucket.tokens) / perSecond), retryAfter
+}
+
+// secondsToDuration converts a number of seconds into a duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
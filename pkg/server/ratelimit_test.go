@@ -0,0 +1,185 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// =============================================================================
+// TEST SUITE SETUP
+// =============================================================================
+
+type RateLimitUnitSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func TestRateLimitUnitSuite(t *testing.T) {
+	suite.Run(t, &RateLimitUnitSuite{})
+}
+
+func (us *RateLimitUnitSuite) SetupTest() {
+	rateLimit, err := RateLimit(1, 2, "api-key", []string{"secret"}, []string{"/ping=0", "/stats=1/1"}, 100)
+	us.Require().Nil(err)
+
+	us.router = gin.New()
+	us.Require().Nil(us.router.SetTrustedProxies([]string{"192.0.2.10"}))
+	us.router.Use(rateLimit)
+	for _, path := range []string{"/comics", "/search", "/stats", "/ping"} {
+		us.router.GET(path, func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"message": "ok"}) })
+	}
+}
+
+// request performs a GET request on the router from the given remote address, with the given headers
+func (us *RateLimitUnitSuite) request(path, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, path, nil)
+	us.Require().Nil(err)
+	request.RemoteAddr = remoteAddr
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	us.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// =============================================================================
+// UNIT TESTS
+// =============================================================================
+
+func (us *RateLimitUnitSuite) TestRateLimit() {
+	rejected := testutil.ToFloat64(rateLimitedRequests.WithLabelValues("/comics"))
+
+	recorder := us.request("/comics", "192.0.2.1:1234", nil)
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal("2", recorder.Header().Get("RateLimit-Limit"))
+	us.Equal("1", recorder.Header().Get("RateLimit-Remaining"))
+	us.Equal("60", recorder.Header().Get("RateLimit-Reset"))
+	us.Empty(recorder.Header().Get("Retry-After"))
+
+	// The routes that are not overridden share the same bucket, regardless of the port of the client
+	recorder = us.request("/search", "192.0.2.1:5678", nil)
+	us.Equal(http.StatusOK, recorder.Code)
+	us.Equal("0", recorder.Header().Get("RateLimit-Remaining"))
+
+	recorder = us.request("/comics", "192.0.2.1:1234", nil)
+	us.Equal(http.StatusTooManyRequests, recorder.Code)
+	us.Equal(`{"error":"please make sure that no more than 1 requests are made per minute"}`, recorder.Body.String())
+	us.Equal("0", recorder.Header().Get("RateLimit-Remaining"))
+	us.Equal("60", recorder.Header().Get("Retry-After"))
+	us.Equal(rejected+1, testutil.ToFloat64(rateLimitedRequests.WithLabelValues("/comics")))
+
+	testCases := []struct {
+		name           string
+		path           string
+		remoteAddr     string
+		header         http.Header
+		expectedStatus int
+		expectedLimit  string
+	}{
+		{"Other Client", "/comics", "192.0.2.2:1234", nil, 200, "2"},
+		{"Forwarded Client", "/comics", "192.0.2.10:1234", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, 200, "2"},
+		{"Untrusted Forwarded Client", "/comics", "192.0.2.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, 429, "2"},
+		{"Untrusted Real IP", "/comics", "192.0.2.1:1234", http.Header{"X-Real-Ip": {"198.51.100.1"}}, 429, "2"},
+		{"API Key", "/comics", "192.0.2.1:1234", http.Header{"X-Api-Key": {"secret"}}, 200, "2"},
+		{"Unknown API Key", "/comics", "192.0.2.1:1234", http.Header{"X-Api-Key": {"guess"}}, 429, "2"},
+		{"Route Override", "/stats", "192.0.2.1:1234", nil, 200, "1"},
+		{"Exhausted Route Override", "/stats", "192.0.2.1:1234", nil, 429, "1"},
+		{"Unlimited Route", "/ping", "192.0.2.1:1234", nil, 200, ""},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			recorder := us.request(test.path, test.remoteAddr, test.header)
+			us.Equal(test.expectedStatus, recorder.Code)
+			us.Equal(test.expectedLimit, recorder.Header().Get("RateLimit-Limit"))
+		})
+	}
+}
+
+func (us *RateLimitUnitSuite) TestRotatingForwardedFor() {
+	// The clients cannot get a new bucket by sending a new address with every request
+	codes := make([]int, 0, 3)
+	for _, forwardedFor := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		recorder := us.request("/comics", "192.0.2.3:1234", http.Header{"X-Forwarded-For": {forwardedFor}})
+		codes = append(codes, recorder.Code)
+	}
+	us.Equal([]int{200, 200, 429}, codes)
+}
+
+func (us *RateLimitUnitSuite) TestTokenBucket() {
+	now := time.Now()
+	limiter := newRateLimiter(2)
+	limiter.now = func() time.Time { return now }
+	limit := rateLimit{perMinute: 60, burst: 2}
+
+	allowed, remaining, reset, _ := limiter.Take("a", limit)
+	us.True(allowed)
+	us.Equal(1, remaining)
+	us.Equal(time.Second, reset)
+	allowed, remaining, reset, _ = limiter.Take("a", limit)
+	us.True(allowed)
+	us.Equal(0, remaining)
+	us.Equal(2*time.Second, reset)
+
+	// Half a token was refilled
+	now = now.Add(500 * time.Millisecond)
+	allowed, remaining, _, retryAfter := limiter.Take("a", limit)
+	us.False(allowed)
+	us.Equal(0, remaining)
+	us.Equal(500*time.Millisecond, retryAfter)
+
+	// The bucket never holds more than the burst
+	now = now.Add(time.Hour)
+	allowed, remaining, _, _ = limiter.Take("a", limit)
+	us.True(allowed)
+	us.Equal(1, remaining)
+
+	// The least recently used buckets are forgotten, which makes them full again
+	_, _, _, _ = limiter.Take("b", limit)
+	_, _, _, _ = limiter.Take("a", limit)
+	_, _, _, _ = limiter.Take("c", limit)
+	us.Len(limiter.buckets, 2)
+	us.Contains(limiter.buckets, "a")
+	us.NotContains(limiter.buckets, "b")
+	_, remaining, _, _ = limiter.Take("b", limit)
+	us.Equal(1, remaining)
+}
+
+func (us *RateLimitUnitSuite) TestConfiguration() {
+	testCases := []struct {
+		name          string
+		perMinute     int
+		burst         int
+		key           string
+		routeLimits   []string
+		expectedError error
+	}{
+		{"Defaults", 600, 60, "ip", []string{"/ping=0", "/stats=60/10"}, nil},
+		{"Disabled", 0, 60, "unknown", []string{"invalid"}, nil},
+		{"Header Key", 600, 60, "header:X-Client-Id", nil, nil},
+		{"Negative Rate", -1, 60, "ip", nil, errors.New("please make sure that the rate limit is not negative and that the burst is positive")},
+		{"No Burst", 600, 0, "ip", nil, errors.New("please make sure that the rate limit is not negative and that the burst is positive")},
+		{"Unknown Key", 600, 60, "cookie", nil, errors.New("please make sure that the rate limit key is one of 'ip', 'api-key' or 'header:NAME'")},
+		{"Empty Header Key", 600, 60, "header:", nil, errors.New("please make sure that the rate limit key is one of 'ip', 'api-key' or 'header:NAME'")},
+		{"Missing Rate", 600, 60, "ip", []string{"/stats"}, errors.New("please make sure that the route rate limit '/stats' is formatted as ROUTE=RATE[/BURST]")},
+		{"Invalid Burst", 600, 60, "ip", []string{"/stats=60/0"}, errors.New("please make sure that the route rate limit '/stats=60/0' is formatted as ROUTE=RATE[/BURST]")},
+		{"Relative Route", 600, 60, "ip", []string{"stats=60"}, errors.New("please make sure that the route rate limit 'stats=60' is formatted as ROUTE=RATE[/BURST]")},
+	}
+
+	for i := range testCases {
+		test := testCases[i]
+		us.Run(test.name, func() {
+			_, err := RateLimit(test.perMinute, test.burst, test.key, nil, test.routeLimits, 100)
+			us.Equal(test.expectedError, err)
+		})
+	}
+}
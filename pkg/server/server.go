@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/itsemre/go-api-k8s/pkg/config"
 	"github.com/itsemre/go-api-k8s/pkg/controller"
 	log "github.com/itsemre/go-api-k8s/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	ginprometheus "github.com/zsais/go-gin-prometheus"
 )
@@ -28,11 +30,28 @@ type Server struct {
 func NewServer(conf *config.Config, logger *logrus.Logger) *Server {
 	serverAddress := fmt.Sprintf("%s:%s", conf.ServerAddress, conf.ServerPort)
 	router := gin.New()
+	// Only the configured proxies may tell the IP address of the clients
+	if err := router.SetTrustedProxies(conf.TrustedProxies); err != nil {
+		logger.Fatalf("trusted proxies: %s\n", err)
+	}
 	router.Use(gin.Recovery(), log.JSONLogger(logger))
 
 	// Set up prometheus middleware to expose metrics
 	prom := ginprometheus.NewPrometheus("gin")
 	prom.Use(router)
+	if err := controller.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		logger.Fatalf("register metrics: %s\n", err)
+	}
+	if err := RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		logger.Fatalf("register metrics: %s\n", err)
+	}
+
+	// Compress the responses, which sees them as they are once validated by the middleware added by Start
+	compression, err := Compression(conf.CompressionLevel, conf.CompressionMinSize, conf.CompressionExclude)
+	if err != nil {
+		logger.Fatalf("compression: %s\n", err)
+	}
+	router.Use(compression)
 
 	srv := &http.Server{
 		Addr:    serverAddress,
@@ -58,17 +77,71 @@ func (s *Server) Start() error {
 		MaxAge:           time.Duration(s.Config.CORSMaxAge) * time.Hour,
 	}))
 
-	// Get a new controller instance
-	controller := controller.NewController(s.Config)
+	// Reject the clients that make too many requests before doing any work on their behalf
+	rateLimit, err := RateLimit(s.Config.RateLimitPerMinute, s.Config.RateLimitBurst, s.Config.RateLimitKey, s.Config.RateLimitKeys, s.Config.RateLimitRoutes, s.Config.RateLimitMaxClients)
+	if err != nil {
+		return err
+	}
+	s.Router.Use(rateLimit)
 
-	// Assign the Gin handlers to their corresponding URL paths and methods
-	s.Router.GET("/comics", controller.GetComics)
-	s.Router.GET("/ping", controller.Health)
+	// Make the responses cacheable, and answer the clients revalidating them
+	conditional, err := ConditionalRequests(s.Config.HTTPCacheMaxAge, s.Config.HTTPCacheRouteMaxAges)
+	if err != nil {
+		return err
+	}
+	s.Router.Use(conditional)
 
 	// Get context for termination signals
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Open the persistent comic store, if a data directory was configured
+	var store *controller.ComicStore
+	if s.Config.DataDir != "" {
+		if store, err = controller.OpenComicStore(s.Config.DataDir); err != nil {
+			return err
+		}
+		defer store.Close()
+	}
+
+	// Get a new controller instance
+	source := controller.NewComicSource(s.Config)
+	ctrl := controller.NewController(s.Config, source, store)
+	if err := ctrl.LoadIndex(); err != nil {
+		return err
+	}
+	if err := ctrl.LoadImageCache(); err != nil {
+		return err
+	}
+
+	// Assign the Gin handlers to their corresponding URL paths and methods
+	s.Router.GET("/comics", ctrl.GetComics)
+	s.Router.GET("/comics/stream", ctrl.GetComicsStream)
+	s.Router.GET("/comics/latest", ctrl.GetLatestComic)
+	s.Router.GET("/comics/on-this-day", ctrl.GetComicsOnThisDay)
+	s.Router.GET("/comics/random", ctrl.GetRandomComic)
+	s.Router.GET("/comics/:num", ctrl.GetComic)
+	s.Router.GET("/comics/:num/transcript", ctrl.GetComicTranscript)
+	s.Router.GET("/comics/:num/image", ctrl.GetComicImage)
+	s.Router.GET("/search", ctrl.Search)
+	s.Router.GET("/stats", ctrl.GetStats)
+	s.Router.GET("/feeds/comics.rss", ctrl.GetRSSFeed)
+	s.Router.GET("/feeds/comics.atom", ctrl.GetAtomFeed)
+	s.Router.GET("/calendar.ics", ctrl.GetCalendar)
+	s.Router.GET("/ping", ctrl.Health)
+	s.Router.DELETE("/admin/cache", RequireAdminToken(s.Config.AdminToken), ctrl.PurgeCache)
+
+	// Keep the store in sync with upstream in the background until the termination signal
+	var wg sync.WaitGroup
+	if store != nil && s.Config.SyncInterval > 0 {
+		syncer := controller.NewSyncer(store, source, ctrl.Index, time.Duration(s.Config.SyncInterval)*time.Second, s.Logger)
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			syncer.Run(ctx)
+		}(ctx)
+	}
+
 	// Initializing the server in a goroutine so that it won't block the graceful shutdown handling below
 	go func() {
 		if err := s.HTTPServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -90,6 +163,9 @@ func (s *Server) Start() error {
 		s.Logger.Fatalf("Server forced to shutdown: %s\n", err)
 	}
 
+	// Wait for the background sync to stop before the store is closed
+	wg.Wait()
+
 	s.Logger.Println("Server exiting")
 	return nil
 }